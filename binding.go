@@ -0,0 +1,460 @@
+package golitekit
+
+import (
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// the struct tags Bind reads a field from, in order of precedence
+var bindSources = []string{"path", "query", "header", "cookie", "form"}
+
+var (
+	timeType            = reflect.TypeOf(time.Time{})
+	durationType        = reflect.TypeOf(time.Duration(0))
+	fileHeaderType      = reflect.TypeOf((*multipart.FileHeader)(nil))
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// FieldError is the error of a struct field that could not be bound or
+// failed validation.
+type FieldError struct {
+	// Field is the path of the field in the struct, such as Filter.Page, it
+	// is empty for a body that cannot be decoded at all
+	Field string `json:"field,omitempty"`
+	// Source is the struct tag the value comes from, or body
+	Source string `json:"source,omitempty"`
+	Key    string `json:"key,omitempty"`
+	// Rule is the validate rule the field failed
+	Rule string `json:"rule,omitempty"`
+	Msg  string `json:"msg"`
+}
+
+func (e *FieldError) Error() string {
+	if e.Field == "" {
+		return fmt.Sprintf("%s: %s", e.Source, e.Msg)
+	}
+	if e.Key == "" {
+		return fmt.Sprintf("%s: %s", e.Field, e.Msg)
+	}
+	return fmt.Sprintf("%s (%s %q): %s", e.Field, e.Source, e.Key, e.Msg)
+}
+
+// FieldErrors are the errors of every field that could not be bound or
+// failed validation. Returned from Serve, they are answered with a 400
+// Response listing them, see ContextAsMiddleware.
+type FieldErrors []*FieldError
+
+func (es FieldErrors) Error() string {
+	msgs := make([]string, len(es))
+	for i, e := range es {
+		msgs[i] = e.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Bind fills the struct v points to from the request, see bind, and checks
+// it, see ValidateStruct.
+func (c *BaseController) Bind(v any) error {
+	body, err := c.RawBody()
+	if err != nil {
+		return err
+	}
+	if err := bind(c.request, c.gcx.routerParams, body, v); err != nil {
+		return err
+	}
+	return ValidateStruct(v)
+}
+
+// bind fills the struct v points to. Fields are first set to their default
+// tag when zero, then decoded from the body with the codec of its
+// Content-Type, see RegisterCodec, then read from the router params, the
+// query, the headers, the cookies and the urlencoded or multipart form with
+// the path, query, header, cookie and form tags:
+//
+//	type ListRequest struct {
+//		ID     int       `path:"id"`
+//		Page   int       `query:"page" default:"1"`
+//		Tags   []string  `query:"tag"`
+//		Since  time.Time `query:"since" time_format:"2006-01-02"`
+//		Token  string    `header:"X-Token"`
+//		Filter struct {
+//			Owner *string `query:"owner"`
+//		}
+//		Avatar *multipart.FileHeader `form:"avatar"`
+//	}
+//
+// Untagged struct fields are bound recursively. A value that cannot be parsed
+// into its field is reported as a FieldError, bind returns them all together
+// as FieldErrors. A body no codec is registered for fails with
+// ErrUnsupportedMediaType.
+func bind(req *http.Request, params Params, body []byte, v any) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("bind: %T is not a pointer to a struct", v)
+	}
+
+	b := &binder{req: req, params: params, form: req.PostForm}
+	b.walk(rv.Elem(), "", b.bindDefault)
+	if err := b.decodeBody(v, body); err != nil {
+		return err
+	}
+	b.walk(rv.Elem(), "", b.bindField)
+
+	if len(b.errs) > 0 {
+		return b.errs
+	}
+	return nil
+}
+
+type binder struct {
+	req    *http.Request
+	params Params
+	query  url.Values
+	form   url.Values
+	errs   FieldErrors
+}
+
+// walk calls bind on every exported leaf field of v, descending into untagged
+// struct fields. It reports whether bind set any field, so that nil struct
+// pointers are only allocated when one of their fields is set.
+func (b *binder) walk(v reflect.Value, prefix string, bind func(f reflect.StructField, fv reflect.Value, name string) bool) bool {
+	set := false
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() && !f.Anonymous {
+			continue
+		}
+		fv := v.Field(i)
+		name := prefix + f.Name
+
+		if !hasBindTag(f) {
+			ft := f.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct && !isScalarType(ft) {
+				if f.Anonymous {
+					name = prefix
+				} else {
+					name += "."
+				}
+				if fv.Kind() != reflect.Pointer {
+					set = b.walk(fv, name, bind) || set
+					continue
+				}
+				if !f.IsExported() {
+					continue
+				}
+				if !fv.IsNil() {
+					set = b.walk(fv.Elem(), name, bind) || set
+					continue
+				}
+				nv := reflect.New(ft)
+				if b.walk(nv.Elem(), name, bind) {
+					fv.Set(nv)
+					set = true
+				}
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+		set = bind(f, fv, name) || set
+	}
+	return set
+}
+
+func hasBindTag(f reflect.StructField) bool {
+	if _, ok := f.Tag.Lookup("default"); ok {
+		return true
+	}
+	for _, source := range bindSources {
+		if _, ok := f.Tag.Lookup(source); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (b *binder) bindDefault(f reflect.StructField, fv reflect.Value, name string) bool {
+	def, ok := f.Tag.Lookup("default")
+	if !ok || !fv.IsZero() {
+		return false
+	}
+	vals := []string{def}
+	if fv.Kind() == reflect.Slice {
+		vals = strings.Split(def, ",")
+	}
+	if err := setValues(fv, vals, f); err != nil {
+		b.errs = append(b.errs, &FieldError{Field: name, Source: "default", Msg: err.Error()})
+		return false
+	}
+	return true
+}
+
+func (b *binder) bindField(f reflect.StructField, fv reflect.Value, name string) bool {
+	for _, source := range bindSources {
+		key, ok := f.Tag.Lookup(source)
+		if !ok || key == "" || key == "-" {
+			continue
+		}
+		if source == "form" && isFileType(fv.Type()) {
+			return b.bindFiles(fv, key)
+		}
+		vals := b.values(source, key)
+		if len(vals) == 0 {
+			continue
+		}
+		if err := setValues(fv, vals, f); err != nil {
+			b.errs = append(b.errs, &FieldError{Field: name, Source: source, Key: key, Msg: err.Error()})
+			return false
+		}
+		return true
+	}
+	return false
+}
+
+func (b *binder) values(source, key string) []string {
+	if source == "form" {
+		return b.form[key]
+	}
+	if b.req == nil {
+		return nil
+	}
+	switch source {
+	case "path":
+		if v, ok := b.params.Lookup(key); ok {
+			return []string{v}
+		}
+	case "query":
+		if b.query == nil {
+			b.query = b.req.URL.Query()
+		}
+		return b.query[key]
+	case "header":
+		return b.req.Header.Values(key)
+	case "cookie":
+		if cookie, err := b.req.Cookie(key); err == nil {
+			return []string{cookie.Value}
+		}
+	}
+	return nil
+}
+
+func isFileType(t reflect.Type) bool {
+	return t == fileHeaderType || t == reflect.SliceOf(fileHeaderType)
+}
+
+// bindFiles sets *multipart.FileHeader and []*multipart.FileHeader fields from
+// a multipart form.
+func (b *binder) bindFiles(fv reflect.Value, key string) bool {
+	if b.req == nil || b.req.MultipartForm == nil || len(b.req.MultipartForm.File[key]) == 0 {
+		return false
+	}
+	files := b.req.MultipartForm.File[key]
+	if fv.Kind() == reflect.Slice {
+		fv.Set(reflect.ValueOf(files))
+	} else {
+		fv.Set(reflect.ValueOf(files[0]))
+	}
+	return true
+}
+
+// decodeBody decodes the body into v with the codec of its Content-Type, see
+// RegisterCodec. Forms are left to the form tags, since they are parsed
+// already, and a body of no Content-Type is ignored.
+func (b *binder) decodeBody(v any, body []byte) error {
+	ct := b.req.Header.Get("Content-Type")
+	if len(body) == 0 || ct == "" {
+		return nil
+	}
+	mt, _, err := mime.ParseMediaType(ct)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrUnsupportedMediaType, ct)
+	}
+	if mt == MIMEForm || mt == MIMEMultipart {
+		return nil
+	}
+	codec, ok := lookupCodec(mt)
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnsupportedMediaType, mt)
+	}
+
+	err = codec.Decode(body, v)
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		b.errs = append(b.errs, &FieldError{
+			Field:  fieldPath(reflect.TypeOf(v), typeErr.Field),
+			Source: "body",
+			Key:    typeErr.Field,
+			Msg:    fmt.Sprintf("cannot decode %s into %s", typeErr.Value, typeErr.Type),
+		})
+		return nil
+	}
+	if err != nil {
+		return FieldErrors{{Source: "body", Msg: err.Error()}}
+	}
+	return nil
+}
+
+// fieldPath maps the dotted JSON key path of a decode error to the path of
+// the field in t, such as filter.owner to Filter.Owner, the way FieldErrors
+// name fields. Keys matching no field are kept as they are.
+func fieldPath(t reflect.Type, keyPath string) string {
+	keys := strings.Split(keyPath, ".")
+	names := make([]string, 0, len(keys))
+	for _, key := range keys {
+		for t.Kind() == reflect.Pointer || t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+			t = t.Elem()
+		}
+		if t.Kind() == reflect.Map {
+			names = append(names, key)
+			t = t.Elem()
+			continue
+		}
+		f, ok := jsonField(t, key)
+		if !ok {
+			return strings.Join(append(names, keys[len(names):]...), ".")
+		}
+		names = append(names, f.Name)
+		t = f.Type
+	}
+	return strings.Join(names, ".")
+}
+
+// jsonField returns the field of struct type t encoding/json decodes key
+// into, promoted fields included.
+func jsonField(t reflect.Type, key string) (reflect.StructField, bool) {
+	if t.Kind() != reflect.Struct {
+		return reflect.StructField{}, false
+	}
+	var folded *reflect.StructField
+	for _, f := range reflect.VisibleFields(t) {
+		if !f.IsExported() || f.Anonymous && f.Tag.Get("json") == "" {
+			continue
+		}
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		switch {
+		case name == "-":
+			continue
+		case name == "":
+			name = f.Name
+		}
+		if name == key {
+			return f, true
+		}
+		if folded == nil && strings.EqualFold(name, key) {
+			folded = &f
+		}
+	}
+	if folded != nil {
+		return *folded, true
+	}
+	return reflect.StructField{}, false
+}
+
+func isScalarType(t reflect.Type) bool {
+	return t == timeType || reflect.PointerTo(t).Implements(textUnmarshalerType)
+}
+
+// setValues sets fv from vals, a slice field takes every value and other
+// fields the first one.
+func setValues(fv reflect.Value, vals []string, f reflect.StructField) error {
+	switch {
+	case fv.Kind() == reflect.Pointer:
+		nv := reflect.New(fv.Type().Elem())
+		if err := setValues(nv.Elem(), vals, f); err != nil {
+			return err
+		}
+		fv.Set(nv)
+		return nil
+	case fv.Kind() == reflect.Slice && fv.Type().Elem().Kind() != reflect.Uint8:
+		slice := reflect.MakeSlice(fv.Type(), len(vals), len(vals))
+		for i, val := range vals {
+			if err := setValue(slice.Index(i), strings.TrimSpace(val), f); err != nil {
+				return err
+			}
+		}
+		fv.Set(slice)
+		return nil
+	}
+	return setValue(fv, vals[0], f)
+}
+
+func setValue(fv reflect.Value, val string, f reflect.StructField) error {
+	switch fv.Type() {
+	case timeType:
+		layout := f.Tag.Get("time_format")
+		if layout == "" {
+			layout = time.RFC3339
+		}
+		t, err := time.Parse(layout, val)
+		if err != nil {
+			return fmt.Errorf("invalid time %q, expected %s", val, layout)
+		}
+		fv.Set(reflect.ValueOf(t))
+		return nil
+	case durationType:
+		d, err := time.ParseDuration(val)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q", val)
+		}
+		fv.SetInt(int64(d))
+		return nil
+	}
+
+	if fv.CanAddr() && fv.Addr().Type().Implements(textUnmarshalerType) {
+		return fv.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(val))
+	}
+
+	switch fv.Kind() {
+	case reflect.String:
+		fv.SetString(val)
+	case reflect.Bool:
+		if val == "on" {
+			fv.SetBool(true)
+			return nil
+		}
+		bval, err := strconv.ParseBool(val)
+		if err != nil {
+			return fmt.Errorf("invalid bool %q", val)
+		}
+		fv.SetBool(bval)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		ival, err := strconv.ParseInt(val, 10, fv.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", val)
+		}
+		fv.SetInt(ival)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		uval, err := strconv.ParseUint(val, 10, fv.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid unsigned integer %q", val)
+		}
+		fv.SetUint(uval)
+	case reflect.Float32, reflect.Float64:
+		fval, err := strconv.ParseFloat(val, fv.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid number %q", val)
+		}
+		fv.SetFloat(fval)
+	case reflect.Slice:
+		// []byte
+		fv.SetBytes([]byte(val))
+	default:
+		return fmt.Errorf("unsupported type %s", fv.Type())
+	}
+	return nil
+}
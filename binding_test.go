@@ -0,0 +1,186 @@
+package golitekit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+type bindFilter struct {
+	Owner *string  `query:"owner"`
+	Sizes []uint16 `query:"size"`
+}
+
+type bindRequest struct {
+	ID      int           `path:"id"`
+	Page    int           `query:"page" default:"1"`
+	Limit   int           `query:"limit" default:"20"`
+	Tags    []string      `query:"tag"`
+	Since   time.Time     `query:"since" time_format:"2006-01-02"`
+	Timeout time.Duration `query:"timeout" default:"5s"`
+	Token   string        `header:"X-Token"`
+	Session string        `cookie:"session"`
+	Filter  bindFilter
+	Extra   *bindFilter
+	Name    string `json:"name"`
+	Active  bool   `json:"active"`
+	private string
+}
+
+func TestBind(t *testing.T) {
+	body := `{"name":"gopher","active":true}`
+	req := httptest.NewRequest(http.MethodPost, "/user/42?limit=50&tag=a&tag=b&since=2024-05-01&owner=me&size=1&size=2", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	req.Header.Set("X-Token", "secret")
+	req.AddCookie(&http.Cookie{Name: "session", Value: "s1"})
+
+	var r bindRequest
+	if err := bind(req, Params{{Key: "id", Value: "42"}}, []byte(body), &r); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	if r.ID != 42 || r.Page != 1 || r.Limit != 50 || r.Timeout != 5*time.Second {
+		t.Errorf("Unexpected ints %d %d %d %s", r.ID, r.Page, r.Limit, r.Timeout)
+	}
+	if len(r.Tags) != 2 || r.Tags[1] != "b" {
+		t.Errorf("Unexpected tags %v", r.Tags)
+	}
+	if !r.Since.Equal(time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Unexpected since %s", r.Since)
+	}
+	if r.Token != "secret" || r.Session != "s1" {
+		t.Errorf("Unexpected header or cookie %q %q", r.Token, r.Session)
+	}
+	if r.Filter.Owner == nil || *r.Filter.Owner != "me" || len(r.Filter.Sizes) != 2 || r.Filter.Sizes[1] != 2 {
+		t.Errorf("Unexpected nested struct %+v", r.Filter)
+	}
+	if r.Extra == nil || *r.Extra.Owner != "me" {
+		t.Errorf("Expected the nested pointer to be allocated, got %+v", r.Extra)
+	}
+	if r.Name != "gopher" || !r.Active {
+		t.Errorf("Unexpected body fields %q %v", r.Name, r.Active)
+	}
+}
+
+func TestBindErrors(t *testing.T) {
+	body := `{"name":1}`
+	req := httptest.NewRequest(http.MethodGet, "/?page=x&since=yesterday&size=70000", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+
+	var r bindRequest
+	err := bind(req, Params{{Key: "id", Value: "abc"}}, []byte(body), &r)
+	var errs FieldErrors
+	if !errors.As(err, &errs) {
+		t.Fatalf("Expected FieldErrors, got %v", err)
+	}
+	fields := make([]string, 0, len(errs))
+	for _, e := range errs {
+		fields = append(fields, e.Field)
+	}
+	want := "Name ID Page Since Filter.Sizes Extra.Sizes"
+	if strings.Join(fields, " ") != want {
+		t.Errorf("Expected errors for %s, got %v", want, errs)
+	}
+	if errs[0].Key != "name" || errs[0].Source != "body" {
+		t.Errorf("Expected the JSON key of the body error, got %+v", errs[0])
+	}
+
+	err = bind(req, nil, []byte(`{"filter":{"owner":1}}`), &bindRequest{})
+	if !errors.As(err, &errs) || errs[0].Field != "Filter.Owner" || errs[0].Key != "filter.owner" {
+		t.Errorf("Expected a Filter.Owner error, got %v", err)
+	}
+
+	if err := bind(req, nil, nil, r); err == nil {
+		t.Errorf("Expected an error binding a non pointer")
+	}
+	err = bind(req, nil, []byte("{"), &r)
+	if !errors.As(err, &errs) || len(errs) != 1 || errs[0].Source != "body" {
+		t.Fatalf("Expected a body error, got %v", err)
+	}
+	if data, _ := json.Marshal(errs[0]); strings.Contains(string(data), `"field"`) {
+		t.Errorf("Expected no field for a malformed body, got %s", data)
+	}
+}
+
+func TestBindForm(t *testing.T) {
+	type formRequest struct {
+		Name   string                  `form:"name"`
+		Agree  bool                    `form:"agree"`
+		Avatar *multipart.FileHeader   `form:"avatar"`
+		Docs   []*multipart.FileHeader `form:"doc"`
+	}
+
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	mw.WriteField("name", "gopher")
+	mw.WriteField("agree", "on")
+	fw, _ := mw.CreateFormFile("avatar", "a.png")
+	fw.Write([]byte("png"))
+	for _, name := range []string{"a.txt", "b.txt"} {
+		fw, _ = mw.CreateFormFile("doc", name)
+		fw.Write([]byte(name))
+	}
+	mw.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/", &buf)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	if err := req.ParseMultipartForm(1 << 20); err != nil {
+		t.Fatal(err)
+	}
+
+	var r formRequest
+	if err := bind(req, nil, nil, &r); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if r.Name != "gopher" || !r.Agree {
+		t.Errorf("Unexpected form fields %q %v", r.Name, r.Agree)
+	}
+	if r.Avatar == nil || r.Avatar.Filename != "a.png" || len(r.Docs) != 2 {
+		t.Errorf("Unexpected files %v %v", r.Avatar, r.Docs)
+	}
+
+	form := url.Values{"name": {"gopher"}, "agree": {"false"}}
+	req = httptest.NewRequest(http.MethodPost, "/?name=query", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.ParseForm()
+	r = formRequest{}
+	if err := bind(req, nil, nil, &r); err != nil || r.Name != "gopher" || r.Agree {
+		t.Errorf("Unexpected urlencoded form %+v, error %v", r, err)
+	}
+}
+
+type bindController struct {
+	BaseController
+}
+
+func (c *bindController) Serve(ctx context.Context) error {
+	var r struct {
+		ID   int    `path:"id"`
+		Name string `json:"name"`
+	}
+	if err := c.Bind(&r); err != nil {
+		return err
+	}
+	return c.ServeJSON(r)
+}
+
+func TestBindController(t *testing.T) {
+	s := newTestServer()
+	s.OnPut("/user/:id", &bindController{})
+
+	req := httptest.NewRequest(http.MethodPut, "/user/7", strings.NewReader(`{"name":"gopher"}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, req)
+	if body := w.Body.String(); body != `{"ID":7,"name":"gopher"}` {
+		t.Errorf("Unexpected body %s", body)
+	}
+}
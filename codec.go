@@ -0,0 +1,312 @@
+package golitekit
+
+import (
+	"encoding"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"github/hsj/GoLiteKit/config"
+	"mime"
+	"net/http"
+	"net/url"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Codec encodes response bodies and decodes request bodies of a media type,
+// see RegisterCodec.
+type Codec interface {
+	Encode(v any) ([]byte, error)
+	Decode(data []byte, v any) error
+}
+
+// CodecFuncs builds a Codec from an encode and a decode function.
+type CodecFuncs struct {
+	EncodeFunc func(v any) ([]byte, error)
+	DecodeFunc config.Decoder
+}
+
+func (c CodecFuncs) Encode(v any) ([]byte, error) {
+	return c.EncodeFunc(v)
+}
+
+func (c CodecFuncs) Decode(data []byte, v any) error {
+	return c.DecodeFunc(data, v)
+}
+
+const (
+	MIMEJSON      = "application/json"
+	MIMEXML       = "application/xml"
+	MIMETextXML   = "text/xml"
+	MIMEYAML      = "application/yaml"
+	MIMEXYAML     = "application/x-yaml"
+	MIMEForm      = "application/x-www-form-urlencoded"
+	MIMEMultipart = "multipart/form-data"
+	MIMEText      = "text/plain"
+)
+
+// ErrUnsupportedMediaType and ErrNotAcceptable are answered with a 415 and a
+// 406 when returned from Serve, Bind and Render return them when no codec
+// matches the request.
+var (
+	ErrUnsupportedMediaType error = NewHTTPError(http.StatusUnsupportedMediaType, "")
+	ErrNotAcceptable        error = NewHTTPError(http.StatusNotAcceptable, "")
+)
+
+var (
+	codecsMu sync.RWMutex
+	// media type -> codec
+	codecs = map[string]Codec{}
+	// in registration order, the first one is picked for */*
+	codecOrder []string
+)
+
+func init() {
+	jsonCodec := CodecFuncs{EncodeFunc: json.Marshal, DecodeFunc: config.JsonDecoder}
+	xmlCodec := CodecFuncs{EncodeFunc: xml.Marshal, DecodeFunc: xml.Unmarshal}
+	yamlCodec := CodecFuncs{EncodeFunc: yaml.Marshal, DecodeFunc: config.YamlDecoder}
+
+	RegisterCodec(MIMEJSON, jsonCodec)
+	RegisterCodec(MIMEXML, xmlCodec)
+	RegisterCodec(MIMETextXML, xmlCodec)
+	RegisterCodec(MIMEYAML, yamlCodec)
+	RegisterCodec(MIMEXYAML, yamlCodec)
+	RegisterCodec(MIMEForm, formCodec{})
+	RegisterCodec(MIMEText, textCodec{})
+}
+
+// RegisterCodec sets the codec of a media type such as application/json, it
+// replaces the codec registered before for the same media type.
+func RegisterCodec(mediaType string, codec Codec) {
+	mediaType = strings.ToLower(mediaType)
+
+	codecsMu.Lock()
+	defer codecsMu.Unlock()
+	if _, ok := codecs[mediaType]; !ok {
+		codecOrder = append(codecOrder, mediaType)
+	}
+	codecs[mediaType] = codec
+}
+
+// lookupCodec returns the codec of mediaType, structured syntax suffixes such
+// as application/vnd.app+json fall back to the codec of their base type.
+func lookupCodec(mediaType string) (Codec, bool) {
+	codecsMu.RLock()
+	defer codecsMu.RUnlock()
+
+	if codec, ok := codecs[mediaType]; ok {
+		return codec, true
+	}
+	if i := strings.LastIndexByte(mediaType, '+'); i >= 0 {
+		codec, ok := codecs["application/"+mediaType[i+1:]]
+		return codec, ok
+	}
+	return nil, false
+}
+
+type mediaRange struct {
+	mediaType string
+	q         float64
+}
+
+// matches reports whether mt, a media type without wildcards, is in r.
+func (r mediaRange) matches(mt string) bool {
+	if r.mediaType == "*/*" {
+		return true
+	}
+	if prefix, ok := strings.CutSuffix(r.mediaType, "*"); ok {
+		return strings.HasPrefix(mt, prefix)
+	}
+	return r.mediaType == mt
+}
+
+// refused reports whether the most specific of ranges matching mt has q=0,
+// such as application/json in "application/json;q=0, */*".
+func refused(ranges []mediaRange, mt string) bool {
+	best, wildcards := -1, 0
+	for i, r := range ranges {
+		n := strings.Count(r.mediaType, "*")
+		if r.matches(mt) && (best < 0 || n < wildcards) {
+			best, wildcards = i, n
+		}
+	}
+	return best >= 0 && ranges[best].q == 0
+}
+
+// negotiate picks the media type and codec of a response from an Accept
+// header, by quality then specificity. A missing Accept header accepts the
+// first registered codec, JSON. Ranges with q=0 refuse the types they match
+// to the wildcards of other ranges.
+func negotiate(accept string) (string, Codec, bool) {
+	if strings.TrimSpace(accept) == "" {
+		accept = "*/*"
+	}
+
+	var ranges, all []mediaRange
+	for _, r := range strings.Split(accept, ",") {
+		mt, params, err := mime.ParseMediaType(r)
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(v, 64); err != nil {
+				continue
+			}
+		}
+		all = append(all, mediaRange{mediaType: mt, q: q})
+		if q > 0 {
+			ranges = append(ranges, mediaRange{mediaType: mt, q: q})
+		}
+	}
+	slices.SortStableFunc(ranges, func(a, b mediaRange) int {
+		if a.q != b.q {
+			return compareFloat(b.q, a.q)
+		}
+		return strings.Count(a.mediaType, "*") - strings.Count(b.mediaType, "*")
+	})
+
+	for _, r := range ranges {
+		if !strings.Contains(r.mediaType, "*") {
+			if codec, ok := lookupCodec(r.mediaType); ok {
+				return r.mediaType, codec, true
+			}
+			continue
+		}
+		prefix := strings.TrimSuffix(r.mediaType, "*")
+		if prefix == "*/" {
+			prefix = ""
+		}
+
+		codecsMu.RLock()
+		for _, mt := range codecOrder {
+			if strings.HasPrefix(mt, prefix) && !refused(all, mt) {
+				codecsMu.RUnlock()
+				return mt, codecs[mt], true
+			}
+		}
+		codecsMu.RUnlock()
+	}
+	return "", nil, false
+}
+
+// formCodec encodes url.Values, string maps and structs with form tags, and
+// decodes into the latter, see Bind.
+type formCodec struct{}
+
+func (formCodec) Encode(v any) ([]byte, error) {
+	var values url.Values
+	switch v := v.(type) {
+	case url.Values:
+		values = v
+	case map[string][]string:
+		values = v
+	case map[string]string:
+		values = make(url.Values, len(v))
+		for k, s := range v {
+			values.Set(k, s)
+		}
+	default:
+		rv := reflect.Indirect(reflect.ValueOf(v))
+		if rv.Kind() != reflect.Struct {
+			return nil, fmt.Errorf("form: cannot encode %T", v)
+		}
+		values = make(url.Values)
+		for i := 0; i < rv.NumField(); i++ {
+			f := rv.Type().Field(i)
+			key := f.Tag.Get("form")
+			if key == "" || key == "-" || !f.IsExported() {
+				continue
+			}
+			fv := rv.Field(i)
+			if fv.Kind() == reflect.Slice && fv.Type().Elem().Kind() != reflect.Uint8 {
+				for j := 0; j < fv.Len(); j++ {
+					values.Add(key, fmt.Sprint(fv.Index(j).Interface()))
+				}
+				continue
+			}
+			values.Set(key, fmt.Sprint(fv.Interface()))
+		}
+	}
+	return []byte(values.Encode()), nil
+}
+
+func (formCodec) Decode(data []byte, v any) error {
+	values, err := url.ParseQuery(string(data))
+	if err != nil {
+		return err
+	}
+	if m, ok := v.(*url.Values); ok {
+		*m = values
+		return nil
+	}
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("form: cannot decode into %T", v)
+	}
+	b := &binder{form: values}
+	b.walk(rv.Elem(), "", b.bindField)
+	if len(b.errs) > 0 {
+		return b.errs
+	}
+	return nil
+}
+
+// textCodec encodes strings, bytes, fmt.Stringer and errors, and decodes into
+// strings, bytes and encoding.TextUnmarshaler.
+type textCodec struct{}
+
+func (textCodec) Encode(v any) ([]byte, error) {
+	switch v := v.(type) {
+	case string:
+		return []byte(v), nil
+	case []byte:
+		return v, nil
+	case encoding.TextMarshaler:
+		return v.MarshalText()
+	case fmt.Stringer:
+		return []byte(v.String()), nil
+	case error:
+		return []byte(v.Error()), nil
+	}
+	return []byte(fmt.Sprint(v)), nil
+}
+
+func (textCodec) Decode(data []byte, v any) error {
+	switch v := v.(type) {
+	case *string:
+		*v = string(data)
+	case *[]byte:
+		*v = append((*v)[:0], data...)
+	case encoding.TextUnmarshaler:
+		return v.UnmarshalText(data)
+	default:
+		return fmt.Errorf("text: cannot decode into %T", v)
+	}
+	return nil
+}
+
+// Render encodes data with the codec negotiated from the Accept header, see
+// RegisterCodec, it is the content negotiating counterpart of ServeJSON. It
+// returns ErrNotAcceptable when no registered codec is acceptable.
+func (c *BaseController) Render(data any) error {
+	c.gcx.responseWriter.Header().Add("Vary", "Accept")
+	mt, codec, ok := negotiate(c.request.Header.Get("Accept"))
+	if !ok {
+		return ErrNotAcceptable
+	}
+	body, err := codec.Encode(data)
+	if err != nil {
+		return err
+	}
+	if strings.HasPrefix(mt, "text/") {
+		mt += "; charset=utf-8"
+	}
+	c.gcx.ServeContent(mt, body)
+	return nil
+}
@@ -0,0 +1,91 @@
+package golitekit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestNegotiate(t *testing.T) {
+	cases := []struct {
+		accept string
+		want   string
+	}{
+		{"", MIMEJSON},
+		{"*/*", MIMEJSON},
+		{"application/xml", MIMEXML},
+		{"text/html, application/yaml;q=0.9, */*;q=0.1", MIMEYAML},
+		{"application/json;q=0.5, text/plain", MIMEText},
+		{"application/vnd.app+json", "application/vnd.app+json"},
+		{"text/*;q=0.8, application/xml;q=0.2", MIMETextXML},
+		{"text/html", ""},
+		{"application/json;q=0", ""},
+		{"application/json;q=0, */*", MIMEXML},
+		{"text/*;q=0, */*;q=0.5, text/xml", MIMETextXML},
+		{"text/*;q=0, */*", MIMEJSON},
+		{"*/*;q=0, application/yaml", MIMEYAML},
+	}
+	for _, c := range cases {
+		mt, _, ok := negotiate(c.accept)
+		if mt != c.want || ok != (c.want != "") {
+			t.Errorf("negotiate(%q): expected %q, got %q", c.accept, c.want, mt)
+		}
+	}
+}
+
+type renderItem struct {
+	ID   int    `json:"id" xml:"id" yaml:"id" form:"id"`
+	Name string `json:"name" xml:"name" yaml:"name" form:"name"`
+}
+
+type renderController struct {
+	BaseController
+}
+
+func (c *renderController) Serve(ctx context.Context) error {
+	var item renderItem
+	if err := c.Bind(&item); err != nil {
+		return err
+	}
+	return c.Render(item)
+}
+
+func TestRender(t *testing.T) {
+	s := newTestServer()
+	s.OnPost("/items", &renderController{})
+
+	cases := []struct {
+		contentType string
+		body        string
+		accept      string
+		status      int
+		respType    string
+		resp        string
+	}{
+		{MIMEJSON, `{"id":1,"name":"a"}`, "", http.StatusOK, MIMEJSON, `{"id":1,"name":"a"}`},
+		{MIMEYAML, "id: 2\nname: b\n", MIMEXML, http.StatusOK, MIMEXML, `<renderItem><id>2</id><name>b</name></renderItem>`},
+		{MIMEForm, "id=3&name=c", "application/x-yaml", http.StatusOK, "application/x-yaml", "id: 3\nname: c\n"},
+		{MIMETextXML, `<renderItem><id>4</id><name>d</name></renderItem>`, MIMEForm, http.StatusOK, MIMEForm, "id=4&name=d"},
+		{MIMEJSON, `{"id":5}`, "text/html", http.StatusNotAcceptable, "", `{"status":406,"msg":"Not Acceptable"}`},
+		{"application/msgpack", `x`, "", http.StatusUnsupportedMediaType, "", `{"status":415,"msg":"Unsupported Media Type"}`},
+	}
+	for _, c := range cases {
+		req := httptest.NewRequest(http.MethodPost, "/items", strings.NewReader(c.body))
+		req.Header.Set("Content-Type", c.contentType)
+		req.Header.Set("Accept", c.accept)
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, req)
+
+		if w.Code != c.status {
+			t.Errorf("%s -> %s: expected status %d, got %d", c.contentType, c.accept, c.status, w.Code)
+		}
+		if c.respType != "" && w.Header().Get("Content-Type") != c.respType {
+			t.Errorf("%s -> %s: expected Content-Type %s, got %s", c.contentType, c.accept, c.respType, w.Header().Get("Content-Type"))
+		}
+		if w.Body.String() != c.resp {
+			t.Errorf("%s -> %s: expected body %q, got %q", c.contentType, c.accept, c.resp, w.Body.String())
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package golitekit
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	uuidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+	builtinConstraints = map[string]func(string) bool{
+		"int": func(s string) bool {
+			_, err := strconv.ParseInt(s, 10, 64)
+			return err == nil
+		},
+		"uint": func(s string) bool {
+			_, err := strconv.ParseUint(s, 10, 64)
+			return err == nil
+		},
+		"float": func(s string) bool {
+			_, err := strconv.ParseFloat(s, 64)
+			return err == nil
+		},
+		"alpha": func(s string) bool {
+			return s != "" && strings.IndexFunc(s, func(r rune) bool {
+				return !('a' <= r && r <= 'z' || 'A' <= r && r <= 'Z')
+			}) < 0
+		},
+		"alnum": func(s string) bool {
+			return s != "" && strings.IndexFunc(s, func(r rune) bool {
+				return !('a' <= r && r <= 'z' || 'A' <= r && r <= 'Z' || '0' <= r && r <= '9')
+			}) < 0
+		},
+		"uuid": uuidPattern.MatchString,
+	}
+)
+
+// paramConstraint restricts the values a wild segment accepts, e.g.
+// :id<int>, :uuid<uuid> or :slug<[a-z0-9-]+>. Anything that is not a builtin
+// name is compiled as a regular expression matching the whole segment, so it
+// can not contain a slash.
+type paramConstraint struct {
+	expr  string
+	match func(string) bool
+}
+
+// parseWildWord splits a wild segment such as :id<int> into its name and
+// constraint, the constraint is nil when the segment has none.
+func parseWildWord(word string) (string, *paramConstraint, error) {
+	name := word[1:]
+	i := strings.IndexByte(name, '<')
+	if i < 0 {
+		return name, nil, nil
+	}
+	if !strings.HasSuffix(name, ">") || i == len(name)-2 {
+		return "", nil, fmt.Errorf("invalid constraint %q", word)
+	}
+
+	expr := name[i+1 : len(name)-1]
+	name = name[:i]
+	if match, ok := builtinConstraints[expr]; ok {
+		return name, &paramConstraint{expr: expr, match: match}, nil
+	}
+	re, err := regexp.Compile("^(?:" + expr + ")$")
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid constraint %q: %v", word, err)
+	}
+	return name, &paramConstraint{expr: expr, match: re.MatchString}, nil
+}
+
+func (c *paramConstraint) String() string {
+	if c == nil {
+		return ""
+	}
+	return c.expr
+}
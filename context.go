@@ -8,6 +8,7 @@ import (
 	"net/http"
 	"strconv"
 	"sync"
+	"sync/atomic"
 )
 
 const (
@@ -68,18 +69,78 @@ type ContextOption func(*Context)
 type Context struct {
 	request        *http.Request
 	responseWriter http.ResponseWriter
-	routerParams   map[string]string
-	logger         logger.Logger
-	panicLogger    *logger.PanicLogger
-
+	routerParams   Params
+	// route is the matched route, nil when routing failed
+	route *Route
+	// controller is the controller serving the request
+	controller Controller
+	// errorHandler answers the error the request failed with
+	errorHandler ErrorHandler
+	logger       logger.Logger
+	panicLogger  *logger.PanicLogger
+
+	// status is written before the body when it is not zero
+	status int
+	// header is merged into the response headers before the status
+	header       http.Header
 	rawResponse  any
 	jsonResponse any
 	rawFile      []byte
 	rawExt       string
 	rawHtml      string
+	content      []byte
+	contentType  string
 
 	data     map[string]any
 	dataLock sync.Mutex
+
+	// detached is set when the request is abandoned while still in use, e.g.
+	// on timeout, so that the context is not recycled
+	detached atomic.Bool
+	// writeMu serializes detaching with the writes of handlers writing the
+	// response directly, see handlerWriter, written is set once they did
+	writeMu sync.Mutex
+	written bool
+}
+
+var contextPool = sync.Pool{
+	New: func() any {
+		return &Context{
+			data: make(map[string]any),
+		}
+	},
+}
+
+// acquireContext returns a pooled Context, it is recycled by releaseContext
+// once the request is served and must not be retained beyond that.
+func acquireContext() *Context {
+	return contextPool.Get().(*Context)
+}
+
+func releaseContext(gcx *Context) {
+	if gcx.detached.Load() {
+		return
+	}
+	params, data := gcx.routerParams[:0], gcx.data
+	clear(data)
+	*gcx = Context{
+		routerParams: params,
+		data:         data,
+	}
+	contextPool.Put(gcx)
+}
+
+func (ctx *Context) detach() {
+	ctx.writeMu.Lock()
+	defer ctx.writeMu.Unlock()
+	ctx.detached.Store(true)
+}
+
+// responseWritten reports whether a handler wrote the response directly.
+func (ctx *Context) responseWritten() bool {
+	ctx.writeMu.Lock()
+	defer ctx.writeMu.Unlock()
+	return ctx.written
 }
 
 func GetContext(ctx context.Context) *Context {
@@ -141,12 +202,18 @@ func WithResponseWriter(w http.ResponseWriter) ContextOption {
 	}
 }
 
-func WithRouterParams(params map[string]string) ContextOption {
+func WithRouterParams(params Params) ContextOption {
 	return func(gcx *Context) {
 		gcx.routerParams = params
 	}
 }
 
+func WithErrorHandler(h ErrorHandler) ContextOption {
+	return func(gcx *Context) {
+		gcx.errorHandler = h
+	}
+}
+
 func WithLogger(logger logger.Logger) ContextOption {
 	return func(gcx *Context) {
 		gcx.logger = logger
@@ -167,10 +234,37 @@ func (ctx *Context) ResponseWriter() http.ResponseWriter {
 	return ctx.responseWriter
 }
 
-func (ctx *Context) RouterParams() map[string]string {
+func (ctx *Context) RouterParams() Params {
 	return ctx.routerParams
 }
 
+// RouteMeta returns the metadata of the matched route, see Meta. It is shared
+// by every request of the route and must not be modified.
+func (ctx *Context) RouteMeta() Meta {
+	if ctx.route == nil {
+		return nil
+	}
+	return ctx.route.meta
+}
+
+// RouteVersion returns the API version of the matched route, or "" when it
+// was registered for no version, see Version.
+func (ctx *Context) RouteVersion() string {
+	if ctx.route == nil {
+		return ""
+	}
+	return ctx.route.version
+}
+
+// RoutePattern returns the pattern of the matched route, such as /user/:id,
+// or "" when the request matched no route.
+func (ctx *Context) RoutePattern() string {
+	if ctx.route == nil {
+		return ""
+	}
+	return ctx.route.path
+}
+
 func (ctx *Context) Logger() logger.Logger {
 	return ctx.logger
 }
@@ -196,12 +290,86 @@ func (ctx *Context) ServeFile(ext string, file []byte) {
 	ctx.rawFile = file
 }
 
+// ServeContent serves body as is with its Content-Type.
+func (ctx *Context) ServeContent(contentType string, body []byte) {
+	ctx.contentType = contentType
+	ctx.content = body
+}
+
+// Status sets the status code of the response, 200 by default.
+func (ctx *Context) Status(code int) {
+	ctx.status = code
+}
+
+// Header returns the headers written with the response. They are dropped
+// when the request fails, see ErrorHandler.
+func (ctx *Context) Header() http.Header {
+	if ctx.header == nil {
+		ctx.header = http.Header{}
+	}
+	return ctx.header
+}
+
+// Redirect answers a redirect to url, code is a 3xx status and defaults to
+// 302 otherwise.
+func (ctx *Context) Redirect(code int, url string) {
+	if code < http.StatusMultipleChoices || code > http.StatusPermanentRedirect {
+		code = http.StatusFound
+	}
+	ctx.Header().Set("Location", url)
+	ctx.status = code
+}
+
+// NoContent answers 204 without a body.
+func (ctx *Context) NoContent() {
+	ctx.status = http.StatusNoContent
+	ctx.rawResponse, ctx.jsonResponse, ctx.rawHtml, ctx.rawFile, ctx.content = nil, nil, "", nil, nil
+}
+
+// Created answers 201 with the location of the created resource, if not
+// empty, and body as JSON, if not nil.
+func (ctx *Context) Created(location string, body any) {
+	if location != "" {
+		ctx.Header().Set("Location", location)
+	}
+	ctx.status = http.StatusCreated
+	if body != nil {
+		ctx.jsonResponse = body
+	}
+}
+
+// bodyAllowed reports whether a response with status may have a body.
+func bodyAllowed(status int) bool {
+	switch {
+	case status == 0:
+		return true
+	case status < http.StatusOK, status == http.StatusNoContent, status == http.StatusNotModified:
+		return false
+	}
+	return true
+}
+
+func (ctx *Context) handleError(err error) {
+	handler := ctx.errorHandler
+	if handler == nil {
+		handler = ResponseErrorHandler
+	}
+	handler(ctx.responseWriter, ctx.request, err)
+}
+
+func (ctx *Context) writeHeader() {
+	if ctx.status != 0 {
+		ctx.responseWriter.WriteHeader(ctx.status)
+	}
+}
+
+// ContextAsMiddleware writes the response buffered on the Context once the
+// controller has served. When the controller or a later middleware fails, the
+// error is answered by the ErrorHandler instead, see HTTPError, and still
+// returned.
 func ContextAsMiddleware() Middleware {
 	return func(ctx context.Context, queue MiddlewareQueue) error {
-		err := queue.Next(ctx)
-		if err != nil {
-			return err
-		}
+		serveErr := queue.Next(ctx)
 
 		if err := ctx.Err(); err != nil {
 			if err == context.Canceled {
@@ -212,44 +380,79 @@ func ContextAsMiddleware() Middleware {
 
 		gcx := GetContext(ctx)
 		if gcx == nil {
-			return nil
+			return serveErr
 		}
 
 		w := gcx.ResponseWriter()
 
-		if gcx.jsonResponse != nil {
-			w.Header().Set("Content-Type", "application/json")
-			if bytes, ok := gcx.jsonResponse.([]byte); ok {
-				w.Write(bytes)
-			} else {
-				jsonData, err := json.Marshal(gcx.jsonResponse)
+		// the buffered response is left alone, a detached controller may
+		// still be writing it
+		if serveErr != nil {
+			if !gcx.responseWritten() {
+				gcx.handleError(serveErr)
+			}
+			return serveErr
+		}
+
+		// encoded before any header is written, so that failing is answered
+		// like any other error
+		if data := gcx.jsonResponse; data != nil {
+			if _, ok := data.([]byte); !ok {
+				jsonData, err := json.Marshal(data)
 				if err != nil {
+					gcx.handleError(err)
 					return err
 				}
-				w.Write(jsonData)
+				gcx.jsonResponse = jsonData
 			}
+		}
+
+		header := w.Header()
+		for key, values := range gcx.header {
+			header[key] = values
+		}
+		if !bodyAllowed(gcx.status) {
+			gcx.writeHeader()
+			return nil
+		}
+
+		if gcx.jsonResponse != nil {
+			w.Header().Set("Content-Type", "application/json")
+			gcx.writeHeader()
+			w.Write(gcx.jsonResponse.([]byte))
 		} else if gcx.rawResponse != nil {
 			switch body := gcx.rawResponse.(type) {
 			case []byte:
 				w.Header().Set("Content-Type", "application/octet-stream")
+				gcx.writeHeader()
 				w.Write(body)
 			case string:
 				w.Header().Set("Content-Type", "text/plain; charset=UTF-8")
+				gcx.writeHeader()
 				w.Write([]byte(body))
 			default:
 				log.Printf("unsupported response data type： %T", gcx.rawResponse)
+				gcx.writeHeader()
 			}
 		} else if gcx.rawHtml != "" {
 			w.Header().Set("Content-Type", "text/html; charset=UTF-8")
+			gcx.writeHeader()
 			w.Write([]byte(gcx.rawHtml))
+		} else if gcx.content != nil {
+			w.Header().Set("Content-Type", gcx.contentType)
+			gcx.writeHeader()
+			w.Write(gcx.content)
 		} else if gcx.rawFile != nil && gcx.rawExt != "" {
 			if contentType := extensionToContentType[gcx.rawExt]; contentType != "" {
 				w.Header().Set("Content-Type", contentType)
 			}
 			w.Header().Set("Content-Length", strconv.FormatInt(int64(len(gcx.rawFile)), 10))
+			gcx.writeHeader()
 			w.Write(gcx.rawFile)
+		} else {
+			gcx.writeHeader()
 		}
 
-		return nil
+		return serveErr
 	}
 }
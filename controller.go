@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"github/hsj/GoLiteKit/logger"
 	"io"
 	"mime"
@@ -14,11 +15,25 @@ import (
 	"strings"
 )
 
+// RequestSizeLimiter limits the request body of a controller. Both limits
+// default to 10M, except that a negative MaxBodySize leaves the body
+// unlimited.
 type RequestSizeLimiter interface {
 	MaxMemorySize() int64
 	MaxBodySize() int64
 }
 
+// BodyStreamer is implemented by controllers reading the request body as a
+// stream, such as large uploads or proxies. When StreamBody reports true, Init
+// neither parses forms nor buffers the body, only MaxBodySize applies.
+type BodyStreamer interface {
+	StreamBody() bool
+}
+
+// ErrBodyTooLarge is answered with a 413 when returned from Serve, RawBody and
+// Bind return it when the body exceeds MaxBodySize.
+var ErrBodyTooLarge error = NewHTTPError(http.StatusRequestEntityTooLarge, "")
+
 type Controller interface {
 	RequestSizeLimiter
 
@@ -57,47 +72,80 @@ func (c *BaseController) Serve(ctx context.Context) error {
 	return nil
 }
 
+// Finalize removes the temporary files multipart forms spooled to disk.
+// Controllers overriding it should call it.
 func (c *BaseController) Finalize(ctx context.Context) error {
+	if c.request != nil && c.request.MultipartForm != nil {
+		return c.request.MultipartForm.RemoveAll()
+	}
 	return nil
 }
 
+// parseBody limits the body to MaxBodySize and parses urlencoded and
+// multipart forms, other bodies are left unread until RawBody is called. The
+// limits and the BodyStreamer opt-out are those of the routed controller,
+// which embeds c.
 func (c *BaseController) parseBody() error {
-	maxMemorySize := c.MaxMemorySize()
+	var limiter RequestSizeLimiter = c
+	if c.gcx.controller != nil {
+		limiter = c.gcx.controller
+	}
+	maxMemorySize := limiter.MaxMemorySize()
 	if maxMemorySize <= 0 {
 		maxMemorySize = 10 << 20 // 10M
 	}
-	maxBodySize := c.MaxBodySize()
-	if maxBodySize <= 0 {
+	maxBodySize := limiter.MaxBodySize()
+	if maxBodySize == 0 {
 		maxBodySize = 10 << 20 // 10M
 	}
 
 	httpReq := c.request
-	httpReq.Body = http.MaxBytesReader(c.gcx.responseWriter, c.request.Body, maxBodySize)
-
-	var err error
-	ct := c.request.Header.Get("Content-Type")
+	if httpReq.Body == nil {
+		return nil
+	}
+	if maxBodySize > 0 {
+		httpReq.Body = http.MaxBytesReader(c.gcx.responseWriter, httpReq.Body, maxBodySize)
+	}
 
-	switch ct {
-	case "application/x-www-form-urlencoded":
-		err = c.request.ParseForm()
-	case "multipart/form-data":
-		err = c.request.ParseMultipartForm(maxMemorySize)
-	default:
-		if httpReq.Body != nil {
-			originBody := httpReq.Body
-			// capable of reading data multiple times
-			c.rawBody, err = io.ReadAll(originBody)
-			if err != nil {
-				return err
-			}
-			defer originBody.Close()
-			httpReq.Body = io.NopCloser(bytes.NewBuffer(c.rawBody))
-		}
+	if streamer, ok := limiter.(BodyStreamer); ok && streamer.StreamBody() {
+		return nil
 	}
 
+	mt, _, err := mime.ParseMediaType(httpReq.Header.Get("Content-Type"))
+	if err != nil {
+		return nil
+	}
+	switch mt {
+	case MIMEForm:
+		err = httpReq.ParseForm()
+	case MIMEMultipart:
+		err = httpReq.ParseMultipartForm(maxMemorySize)
+	}
 	return err
 }
 
+// RawBody returns the request body, reading it on the first call. The body
+// stays readable from the request afterwards. Forms are parsed by Init, their
+// body is consumed already unless the controller is a BodyStreamer.
+func (c *BaseController) RawBody() ([]byte, error) {
+	if c.rawBody != nil || c.request.Body == nil {
+		return c.rawBody, nil
+	}
+
+	body, err := io.ReadAll(c.request.Body)
+	c.request.Body.Close()
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			return nil, ErrBodyTooLarge
+		}
+		return nil, err
+	}
+	c.rawBody = body
+	c.request.Body = io.NopCloser(bytes.NewReader(body))
+	return body, nil
+}
+
 func (c *BaseController) ServeRawData(data any) {
 	c.gcx.ServeRawData(data)
 }
@@ -111,6 +159,37 @@ func (c *BaseController) ServeJSON(data any) error {
 	return nil
 }
 
+func (c *BaseController) Status(code int) {
+	c.gcx.Status(code)
+}
+
+func (c *BaseController) Header() http.Header {
+	return c.gcx.Header()
+}
+
+func (c *BaseController) Redirect(code int, url string) {
+	c.gcx.Redirect(code, url)
+}
+
+func (c *BaseController) NoContent() {
+	c.gcx.NoContent()
+}
+
+// Created answers 201 with the location of the created resource and body as
+// JSON, see Context.Created.
+func (c *BaseController) Created(location string, body any) error {
+	if body == nil {
+		c.gcx.Created(location, nil)
+		return nil
+	}
+	jsonData, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	c.gcx.Created(location, jsonData)
+	return nil
+}
+
 func (c *BaseController) QueryInt(key string, def int) int {
 	params := c.request.URL.Query()
 	if vals, ok := params[key]; ok {
@@ -262,16 +341,14 @@ func (c *BaseController) FormFile(key string) (multipart.File, *multipart.FileHe
 }
 
 func (c *BaseController) RouterParamString(key string, def string) string {
-	params := c.gcx.routerParams
-	if val, ok := params[key]; ok && val != "" {
+	if val := c.gcx.routerParams.Get(key); val != "" {
 		return val
 	}
 	return def
 }
 
 func (c *BaseController) RouterParamInt(key string, def int) int {
-	params := c.gcx.routerParams
-	if val, ok := params[key]; ok && val != "" {
+	if val := c.gcx.routerParams.Get(key); val != "" {
 		if ival, err := strconv.Atoi(val); err == nil {
 			return ival
 		}
@@ -280,8 +357,7 @@ func (c *BaseController) RouterParamInt(key string, def int) int {
 }
 
 func (c *BaseController) RouterParamInt64(key string, def int64) int64 {
-	params := c.gcx.routerParams
-	if val, ok := params[key]; ok && val != "" {
+	if val := c.gcx.routerParams.Get(key); val != "" {
 		if ival, err := strconv.ParseInt(val, 10, 64); err == nil {
 			return ival
 		}
@@ -290,8 +366,7 @@ func (c *BaseController) RouterParamInt64(key string, def int64) int64 {
 }
 
 func (c *BaseController) RouterParamFloat32(key string, def float32) float32 {
-	params := c.gcx.routerParams
-	if val, ok := params[key]; ok && val != "" {
+	if val := c.gcx.routerParams.Get(key); val != "" {
 		if fval, err := strconv.ParseFloat(val, 32); err == nil {
 			return float32(fval)
 		}
@@ -300,8 +375,7 @@ func (c *BaseController) RouterParamFloat32(key string, def float32) float32 {
 }
 
 func (c *BaseController) RouterParamFloat64(key string, def float64) float64 {
-	params := c.gcx.routerParams
-	if val, ok := params[key]; ok && val != "" {
+	if val := c.gcx.routerParams.Get(key); val != "" {
 		if fval, err := strconv.ParseFloat(val, 64); err == nil {
 			return fval
 		}
@@ -310,8 +384,7 @@ func (c *BaseController) RouterParamFloat64(key string, def float64) float64 {
 }
 
 func (c *BaseController) RouterParamBool(key string, def bool) bool {
-	params := c.gcx.routerParams
-	if val, ok := params[key]; ok && val != "" {
+	if val := c.gcx.routerParams.Get(key); val != "" {
 		return val == "1" || strings.ToLower(val) == "true"
 	}
 	return def
@@ -357,17 +430,103 @@ func (c *BaseController) Fatal(ctx context.Context, format string, args ...any)
 	c.logger.Fatal(ctx, format, args...)
 }
 
+// HandlerController adapts a net/http handler to Controller. The handler
+// writes to the response directly and its request carries the request
+// context, so GetContext and the router params are available to it. Once the
+// request timed out, its writes are dropped and fail with
+// http.ErrHandlerTimeout. The body is left unread and unlimited for the
+// handler.
+type HandlerController struct {
+	BaseController
+
+	Handler http.Handler
+}
+
+func (c *HandlerController) StreamBody() bool {
+	return true
+}
+
+func (c *HandlerController) MaxBodySize() int64 {
+	return -1
+}
+
+func (c *HandlerController) Serve(ctx context.Context) error {
+	w := &handlerWriter{gcx: c.gcx, header: http.Header{}}
+	c.Handler.ServeHTTP(w, c.request.WithContext(ctx))
+	return nil
+}
+
+// handlerWriter is the ResponseWriter of a HandlerController, which may still
+// be running after TimeoutMiddleware answered the request. Its headers are
+// copied to the response when it is written.
+type handlerWriter struct {
+	gcx         *Context
+	header      http.Header
+	wroteHeader bool
+}
+
+func (w *handlerWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *handlerWriter) WriteHeader(code int) {
+	w.gcx.writeMu.Lock()
+	defer w.gcx.writeMu.Unlock()
+	if w.gcx.detached.Load() {
+		return
+	}
+	w.writeHeaderLocked(code)
+}
+
+func (w *handlerWriter) writeHeaderLocked(code int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+	w.gcx.written = true
+	header := w.gcx.responseWriter.Header()
+	for key, values := range w.header {
+		header[key] = values
+	}
+	w.gcx.responseWriter.WriteHeader(code)
+}
+
+func (w *handlerWriter) Write(b []byte) (int, error) {
+	w.gcx.writeMu.Lock()
+	defer w.gcx.writeMu.Unlock()
+	if w.gcx.detached.Load() {
+		return 0, http.ErrHandlerTimeout
+	}
+	w.writeHeaderLocked(http.StatusOK)
+	return w.gcx.responseWriter.Write(b)
+}
+
+func (w *handlerWriter) Flush() {
+	w.gcx.writeMu.Lock()
+	defer w.gcx.writeMu.Unlock()
+	if w.gcx.detached.Load() {
+		return
+	}
+	w.writeHeaderLocked(http.StatusOK)
+	if f, ok := w.gcx.responseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
 func controllerAsMiddleware(c Controller) Middleware {
 	return func(ctx context.Context, queue MiddlewareQueue) error {
+		if gcx := GetContext(ctx); gcx != nil {
+			gcx.controller = c
+		}
 		err := c.Init(ctx)
 		if err != nil {
 			return err
 		}
+		// Finalize runs even when Serve fails, to release what Init acquired
 		err = c.Serve(ctx)
-		if err != nil {
-			return err
+		if ferr := c.Finalize(ctx); err == nil {
+			err = ferr
 		}
-		err = c.Finalize(ctx)
 		if err != nil {
 			return err
 		}
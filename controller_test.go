@@ -0,0 +1,167 @@
+package golitekit
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type formController struct {
+	BaseController
+}
+
+func (c *formController) Serve(ctx context.Context) error {
+	c.ServeRawData(c.FormString("name", "none"))
+	return nil
+}
+
+type streamController struct {
+	BaseController
+}
+
+func (c *streamController) StreamBody() bool {
+	return true
+}
+
+func (c *streamController) MaxBodySize() int64 {
+	return 8
+}
+
+func (c *streamController) Serve(ctx context.Context) error {
+	if c.rawBody != nil {
+		c.ServeRawData("buffered")
+		return nil
+	}
+	body, err := io.ReadAll(c.request.Body)
+	if err != nil {
+		return ErrBodyTooLarge
+	}
+	c.ServeRawData("streamed " + string(body))
+	return nil
+}
+
+type rawBodyController struct {
+	BaseController
+}
+
+func (c *rawBodyController) MaxBodySize() int64 {
+	return 8
+}
+
+func (c *rawBodyController) Serve(ctx context.Context) error {
+	if c.rawBody != nil {
+		c.ServeRawData("buffered by Init")
+		return nil
+	}
+	body, err := c.RawBody()
+	if err != nil {
+		return err
+	}
+	again, _ := c.RawBody()
+	rest, _ := io.ReadAll(c.request.Body)
+	c.ServeRawData(string(body) + "|" + string(again) + "|" + string(rest))
+	return nil
+}
+
+func TestParseBody(t *testing.T) {
+	s := newTestServer()
+	s.OnPost("/form", &formController{})
+	s.OnPost("/stream", &streamController{})
+	s.OnPost("/raw", &rawBodyController{})
+
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	mw.WriteField("name", "gopher")
+	mw.Close()
+
+	cases := []struct {
+		path        string
+		contentType string
+		body        string
+		status      int
+		resp        string
+	}{
+		{"/form", mw.FormDataContentType(), buf.String(), http.StatusOK, "gopher"},
+		{"/form", "application/x-www-form-urlencoded; charset=utf-8", "name=urlencoded", http.StatusOK, "urlencoded"},
+		{"/stream", "application/octet-stream", "payload", http.StatusOK, "streamed payload"},
+		{"/stream", "application/x-www-form-urlencoded", "name=x", http.StatusOK, "streamed name=x"},
+		{"/stream", "application/octet-stream", "too large payload", http.StatusRequestEntityTooLarge, `{"status":413,"msg":"Request Entity Too Large"}`},
+		{"/raw", "application/json", `{"a":1}`, http.StatusOK, `{"a":1}|{"a":1}|{"a":1}`},
+		{"/raw", "application/json", `{"a":"too large"}`, http.StatusRequestEntityTooLarge, `{"status":413,"msg":"Request Entity Too Large"}`},
+	}
+	for _, c := range cases {
+		req := httptest.NewRequest(http.MethodPost, c.path, strings.NewReader(c.body))
+		req.Header.Set("Content-Type", c.contentType)
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, req)
+		if w.Code != c.status || w.Body.String() != c.resp {
+			t.Errorf("POST %s %s: expected %d %q, got %d %q", c.path, c.contentType, c.status, c.resp, w.Code, w.Body.String())
+		}
+	}
+}
+
+type responseController struct {
+	BaseController
+}
+
+func (c *responseController) Serve(ctx context.Context) error {
+	c.Header().Set("X-Served-By", "golite")
+	switch c.RouterParamString("kind", "") {
+	case "created":
+		return c.Created("/items/7", map[string]int{"id": 7})
+	case "no-content":
+		c.ServeRawData("dropped")
+		c.NoContent()
+	case "redirect":
+		c.Redirect(http.StatusMovedPermanently, "/new")
+	case "accepted":
+		c.Status(http.StatusAccepted)
+		c.gcx.ServeHTML("<p>queued</p>")
+	case "failed":
+		c.Header().Set("Location", "/never")
+		return Conflict("")
+	case "unencodable":
+		c.Created("/never", nil)
+		c.gcx.ServeJSON(make(chan int))
+	}
+	return nil
+}
+
+func TestResponseHelpers(t *testing.T) {
+	s := newTestServer()
+	s.OnGet("/respond/:kind", &responseController{})
+
+	cases := []struct {
+		kind        string
+		status      int
+		location    string
+		contentType string
+		resp        string
+	}{
+		{"created", http.StatusCreated, "/items/7", "application/json", `{"id":7}`},
+		{"no-content", http.StatusNoContent, "", "", ""},
+		{"redirect", http.StatusMovedPermanently, "/new", "", ""},
+		{"accepted", http.StatusAccepted, "", "text/html; charset=UTF-8", "<p>queued</p>"},
+		{"failed", http.StatusConflict, "", "application/json", `{"status":409,"msg":"Conflict"}`},
+		{"unencodable", http.StatusInternalServerError, "", "application/json", `{"status":500,"msg":"Internal Server Error"}`},
+	}
+	for _, c := range cases {
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/respond/"+c.kind, nil))
+		if w.Code != c.status || w.Body.String() != c.resp {
+			t.Errorf("%s: expected %d %q, got %d %q", c.kind, c.status, c.resp, w.Code, w.Body.String())
+		}
+		h := w.Header()
+		if h.Get("Location") != c.location || h.Get("Content-Type") != c.contentType {
+			t.Errorf("%s: unexpected headers %v", c.kind, h)
+		}
+		if served := h.Get("X-Served-By"); (served == "") != (c.status >= 400) {
+			t.Errorf("%s: unexpected X-Served-By %q", c.kind, served)
+		}
+	}
+}
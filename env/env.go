@@ -2,6 +2,7 @@ package env
 
 import (
 	"github/hsj/GoLiteKit/config"
+	"net/http"
 	"os"
 	"path/filepath"
 	"time"
@@ -9,6 +10,16 @@ import (
 
 var defaultEnv = &Env{}
 
+const (
+	// PathStrict routes request paths verbatim, a path that is not in its
+	// canonical form (trailing slash, empty, "." or ".." segments) is not found
+	PathStrict = "strict"
+	// PathRedirect redirects request paths to their canonical form
+	PathRedirect = "redirect"
+	// PathLenient routes request paths by their canonical form
+	PathLenient = "lenient"
+)
+
 type EnvHttpServer struct {
 	AppName string `toml:"appName"`
 	RunMode string `toml:"runMode"`
@@ -23,6 +34,8 @@ type EnvHttpServer struct {
 	MaxHeaderBytes int `toml:"maxHeaderBytes"`
 
 	EnvRateLimit `toml:"RateLimit"`
+	EnvRouter    `toml:"Router"`
+	EnvUpload    `toml:"Upload"`
 	EnvLogger    `toml:"Logger"`
 	EnvDB        `toml:"DB"`
 	EnvTLSConfig `toml:"TLSConfig"`
@@ -33,6 +46,17 @@ type EnvRateLimit struct {
 	RateBurst int `toml:"rateBurst"`
 }
 
+type EnvRouter struct {
+	PathPolicy      string `toml:"pathPolicy"`
+	RedirectCode    int    `toml:"redirectCode"`
+	CaseInsensitive bool   `toml:"caseInsensitive"`
+	DefaultVersion  string `toml:"defaultVersion"`
+}
+
+type EnvUpload struct {
+	UploadDir string `toml:"dir"`
+}
+
 type EnvLogger struct {
 	Logger string `toml:"configFile"`
 }
@@ -141,6 +165,42 @@ func RateBurst() int {
 	return defaultEnv.RateBurst
 }
 
+func PathPolicy() string {
+	switch defaultEnv.PathPolicy {
+	case PathStrict, PathRedirect:
+		return defaultEnv.PathPolicy
+	}
+	return PathLenient
+}
+
+func RedirectCode() int {
+	if defaultEnv.RedirectCode == http.StatusPermanentRedirect {
+		return http.StatusPermanentRedirect
+	}
+	return http.StatusMovedPermanently
+}
+
+func CaseInsensitive() bool {
+	return defaultEnv.CaseInsensitive
+}
+
+func DefaultVersion() string {
+	return defaultEnv.DefaultVersion
+}
+
+// UploadDir is the directory uploaded files are saved to, relative to the
+// root directory unless absolute.
+func UploadDir() string {
+	dir := defaultEnv.UploadDir
+	if dir == "" {
+		dir = "uploads"
+	}
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(RootDir(), dir)
+	}
+	return dir
+}
+
 func DBConfigFile() string {
 	return filepath.Join(ConfDir(), defaultEnv.DB)
 }
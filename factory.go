@@ -0,0 +1,98 @@
+package golitekit
+
+import (
+	"reflect"
+	"sync"
+)
+
+// Resetter is implemented by controllers that can serve several requests one
+// after the other, see Factory. Reset clears the state a request left on the
+// controller, the state of the embedded BaseController is cleared already.
+type Resetter interface {
+	Reset()
+}
+
+// controllerFactory builds the controller of every request of a route in
+// place of CloneController, see Factory.
+type controllerFactory struct {
+	BaseController
+
+	newFunc func() Controller
+	pool    sync.Pool
+	// name is the type of the controllers built, see RouteInfo
+	name string
+}
+
+// Factory registers a controller by its constructor, e.g.
+//
+//	s.OnGet("/users/:id", golitekit.Factory(func() golitekit.Controller {
+//		return &UserController{DB: db}
+//	}))
+//
+// Every request is served by a controller from newFunc instead of a
+// reflective deep copy of a registered one, so that shared dependencies such
+// as a *gorm.DB or a logger are not copied. Controllers implementing Resetter
+// are pooled and reused once reset. newFunc is called once at registration to
+// describe the route.
+func Factory(newFunc func() Controller) Controller {
+	f := &controllerFactory{newFunc: newFunc}
+	f.pool.New = func() any {
+		return f.newFunc()
+	}
+
+	sample := newFunc()
+	f.name = reflect.TypeOf(sample).String()
+	f.put(sample)
+	return f
+}
+
+// FactoryOf is Factory for controllers whose zero value is ready to serve,
+// e.g. golitekit.FactoryOf[UserController]().
+func FactoryOf[T any, PT interface {
+	*T
+	Controller
+}]() Controller {
+	return Factory(func() Controller {
+		return PT(new(T))
+	})
+}
+
+func (f *controllerFactory) get() Controller {
+	return f.pool.Get().(Controller)
+}
+
+// put returns c to the pool when it can be reset.
+func (f *controllerFactory) put(c Controller) {
+	r, ok := c.(Resetter)
+	if !ok {
+		return
+	}
+	if b, ok := c.(interface{ resetBase() }); ok {
+		b.resetBase()
+	}
+	r.Reset()
+	f.pool.Put(c)
+}
+
+func (c *BaseController) resetBase() {
+	*c = BaseController{}
+}
+
+// newController returns the controller serving a request of a route
+// registered with controller, and a function to call once it is done with.
+func newController(controller Controller) (Controller, func()) {
+	if f, ok := controller.(*controllerFactory); ok {
+		c := f.get()
+		return c, func() { f.put(c) }
+	}
+	return CloneController(controller), func() {}
+}
+
+// controllerName is the type name of the controllers serving the requests of
+// a route registered with controller.
+func controllerName(controller Controller) string {
+	if f, ok := controller.(*controllerFactory); ok {
+		return f.name
+	}
+	return reflect.TypeOf(controller).String()
+}
@@ -0,0 +1,105 @@
+package golitekit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+type factoryDeps struct {
+	Prefix string
+}
+
+type factoryController struct {
+	BaseController
+
+	Deps  *factoryDeps
+	Count int
+}
+
+func (c *factoryController) Serve(ctx context.Context) error {
+	c.Count++
+	c.ServeRawData(c.Deps.Prefix + strconv.Itoa(c.Count))
+	return nil
+}
+
+type pooledController struct {
+	factoryController
+}
+
+func (c *pooledController) Reset() {
+	c.Count = 0
+}
+
+func TestFactory(t *testing.T) {
+	deps := &factoryDeps{Prefix: "n="}
+	var built, pooledBuilt int
+	s := newTestServer()
+	s.OnGet("/plain", Factory(func() Controller {
+		built++
+		return &factoryController{Deps: deps}
+	}))
+	s.OnGet("/pooled", Factory(func() Controller {
+		pooledBuilt++
+		return &pooledController{factoryController{Deps: deps}}
+	}))
+	s.OnGet("/zero", FactoryOf[renderController]())
+
+	for i := 0; i < 3; i++ {
+		for _, path := range []string{"/plain", "/pooled"} {
+			w := httptest.NewRecorder()
+			s.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+			if w.Body.String() != "n=1" {
+				t.Errorf("GET %s: expected fresh controller state, got %q", path, w.Body.String())
+			}
+		}
+	}
+	// one controller per request plus the one describing the route
+	if built != 4 {
+		t.Errorf("Expected 4 controllers built, got %d", built)
+	}
+	// sync.Pool may drop controllers, the pooled route must not build more
+	if pooledBuilt > 4 {
+		t.Errorf("Expected pooled controllers to be reused, got %d built", pooledBuilt)
+	}
+
+	infos := map[string]string{}
+	for _, info := range s.Routes() {
+		infos[info.Pattern] = info.Controller
+	}
+	if infos["/pooled"] != "*golitekit.pooledController" || infos["/zero"] != "*golitekit.renderController" {
+		t.Errorf("Unexpected controller names %v", infos)
+	}
+}
+
+func benchmarkController(b *testing.B, controller Controller) {
+	s := newTestServer()
+	s.OnGet("/bench/:id", controller)
+	req := httptest.NewRequest(http.MethodGet, "/bench/1", nil)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		s.ServeHTTP(httptest.NewRecorder(), req)
+	}
+}
+
+func BenchmarkCloneController(b *testing.B) {
+	benchmarkController(b, &factoryController{Deps: &factoryDeps{}})
+}
+
+func BenchmarkFactory(b *testing.B) {
+	deps := &factoryDeps{}
+	benchmarkController(b, Factory(func() Controller {
+		return &factoryController{Deps: deps}
+	}))
+}
+
+func BenchmarkFactoryPooled(b *testing.B) {
+	deps := &factoryDeps{}
+	benchmarkController(b, Factory(func() Controller {
+		return &pooledController{factoryController{Deps: deps}}
+	}))
+}
@@ -0,0 +1,84 @@
+package golitekit
+
+import (
+	"net/http"
+	"strings"
+)
+
+// Group registers routes under a shared path prefix. Middlewares attached to
+// a group run after the server-wide queue and before the controller of every
+// route registered through it, including routes of nested groups.
+type Group struct {
+	prefix      string
+	middlewares MiddlewareQueue
+	router      *Router
+}
+
+func newGroup(router *Router, prefix string, middlewares MiddlewareQueue) *Group {
+	return &Group{
+		prefix:      joinPath("", prefix),
+		middlewares: middlewares,
+		router:      router,
+	}
+}
+
+func (g *Group) Group(prefix string, middlewares ...Middleware) *Group {
+	mws := g.middlewares.Clone()
+	mws.Use(middlewares...)
+	return newGroup(g.router, g.fullPath(prefix), mws)
+}
+
+func (g *Group) Handle(method, path string, controller Controller, opts ...RouteOption) *Route {
+	return g.router.handle(method, g.fullPath(path), controller, g.middlewares, opts)
+}
+
+func (g *Group) OnGet(path string, controller Controller, opts ...RouteOption) *Route {
+	return g.Handle(http.MethodGet, path, controller, opts...)
+}
+
+func (g *Group) OnHead(path string, controller Controller, opts ...RouteOption) *Route {
+	return g.Handle(http.MethodHead, path, controller, opts...)
+}
+
+func (g *Group) OnPost(path string, controller Controller, opts ...RouteOption) *Route {
+	return g.Handle(http.MethodPost, path, controller, opts...)
+}
+
+func (g *Group) OnPut(path string, controller Controller, opts ...RouteOption) *Route {
+	return g.Handle(http.MethodPut, path, controller, opts...)
+}
+
+func (g *Group) OnPatch(path string, controller Controller, opts ...RouteOption) *Route {
+	return g.Handle(http.MethodPatch, path, controller, opts...)
+}
+
+func (g *Group) OnDelete(path string, controller Controller, opts ...RouteOption) *Route {
+	return g.Handle(http.MethodDelete, path, controller, opts...)
+}
+
+func (g *Group) OnOptions(path string, controller Controller, opts ...RouteOption) *Route {
+	return g.Handle(http.MethodOptions, path, controller, opts...)
+}
+
+func (g *Group) OnAny(path string, controller Controller, opts ...RouteOption) Routes {
+	return g.router.onAny(g.fullPath(path), controller, g.middlewares, opts)
+}
+
+func (g *Group) HandleFunc(method, path string, handler http.HandlerFunc, opts ...RouteOption) *Route {
+	return g.Handle(method, path, &HandlerController{Handler: handler}, opts...)
+}
+
+func (g *Group) Mount(prefix string, handler http.Handler) Routes {
+	if other, ok := handler.(*Server); ok {
+		return g.router.MountRouter(g.fullPath(prefix), &other.router, g.middlewares...)
+	}
+	return g.router.Mount(g.fullPath(prefix), handler, Use(g.middlewares...))
+}
+
+func (g *Group) fullPath(path string) string {
+	return joinPath(g.prefix, path)
+}
+
+func joinPath(prefix, path string) string {
+	return dealSlash(strings.TrimRight(prefix, "/") + dealSlash(path))
+}
@@ -0,0 +1,41 @@
+package golitekit
+
+import (
+	"context"
+)
+
+// jsonHandler serves a typed function, see JSON.
+type jsonHandler[Req, Resp any] struct {
+	RestController
+
+	fn func(ctx context.Context, req *Req) (*Resp, error)
+}
+
+// JSON adapts a typed function into a Controller for small JSON APIs, e.g.
+//
+//	s.OnPost("/users", golitekit.JSON(func(ctx context.Context, req *CreateUser) (*User, error) {
+//		...
+//	}))
+//
+// The request is bound into Req and validated as by Bind, which answers 400
+// on FieldErrors. The Resp returned is served as the Data of a Response, an
+// error returned by fn is answered by the ErrorHandler, see HTTPError.
+func JSON[Req, Resp any](fn func(ctx context.Context, req *Req) (*Resp, error)) Controller {
+	return Factory(func() Controller {
+		return &jsonHandler[Req, Resp]{fn: fn}
+	})
+}
+
+func (h *jsonHandler[Req, Resp]) Serve(ctx context.Context) error {
+	var req Req
+	if err := h.Bind(&req); err != nil {
+		return err
+	}
+
+	resp, err := h.fn(ctx, &req)
+	if err != nil {
+		return err
+	}
+	h.ServeData(resp)
+	return nil
+}
@@ -0,0 +1,54 @@
+package golitekit
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type greetRequest struct {
+	ID   int    `path:"id"`
+	Name string `json:"name" validate:"required"`
+}
+
+type greetResponse struct {
+	Greeting string `json:"greeting"`
+}
+
+func greet(ctx context.Context, req *greetRequest) (*greetResponse, error) {
+	switch req.Name {
+	case "teapot":
+		return nil, ErrNotAcceptable
+	case "boom":
+		return nil, errors.New("database is down")
+	}
+	return &greetResponse{Greeting: "hello " + req.Name}, nil
+}
+
+func TestJSONHandler(t *testing.T) {
+	s := newTestServer()
+	s.OnPost("/greet/:id", JSON(greet))
+
+	cases := []struct {
+		body   string
+		status int
+		resp   string
+	}{
+		{`{"name":"gopher"}`, http.StatusOK, `{"status":0,"msg":"OK","data":{"greeting":"hello gopher"}}`},
+		{`{}`, http.StatusBadRequest, `{"status":400,"msg":"Bad Request","data":[{"field":"Name","rule":"required","msg":"is required"}]}`},
+		{`{"name":"teapot"}`, http.StatusNotAcceptable, `{"status":406,"msg":"Not Acceptable"}`},
+		{`{"name":"boom"}`, http.StatusInternalServerError, `{"status":500,"msg":"Internal Server Error"}`},
+	}
+	for _, c := range cases {
+		req := httptest.NewRequest(http.MethodPost, "/greet/1", strings.NewReader(c.body))
+		req.Header.Set("Content-Type", MIMEJSON)
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, req)
+		if w.Code != c.status || w.Body.String() != c.resp {
+			t.Errorf("%s: expected %d %s, got %d %s", c.body, c.status, c.resp, w.Code, w.Body.String())
+		}
+	}
+}
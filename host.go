@@ -0,0 +1,93 @@
+package golitekit
+
+import (
+	"net"
+	"slices"
+	"strings"
+)
+
+const (
+	// HostParam is the router param capturing the subdomain matched by a
+	// wildcard host pattern such as *.example.com
+	HostParam = "subdomain"
+)
+
+type hostRouter struct {
+	// suffix is the pattern without its leading "*."
+	suffix string
+	router *Router
+}
+
+type hostRouters struct {
+	exact map[string]*Router
+	// longest suffix first
+	wild []*hostRouter
+}
+
+func normalizeHost(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return strings.TrimSuffix(strings.ToLower(host), ".")
+}
+
+// add returns the router of pattern, creating it with newRouter the first
+// time pattern is seen.
+func (hr *hostRouters) add(pattern string, newRouter func() *Router) *Router {
+	pattern = normalizeHost(pattern)
+	if suffix, ok := strings.CutPrefix(pattern, "*."); ok {
+		for _, h := range hr.wild {
+			if h.suffix == suffix {
+				return h.router
+			}
+		}
+		h := &hostRouter{suffix: suffix, router: newRouter()}
+		hr.wild = append(hr.wild, h)
+		slices.SortStableFunc(hr.wild, func(a, b *hostRouter) int {
+			return len(b.suffix) - len(a.suffix)
+		})
+		return h.router
+	}
+
+	if hr.exact == nil {
+		hr.exact = make(map[string]*Router)
+	}
+	if router, ok := hr.exact[pattern]; ok {
+		return router
+	}
+	router := newRouter()
+	hr.exact[pattern] = router
+	return router
+}
+
+// match returns the router of host and the subdomain captured by a wildcard
+// pattern, exact patterns are preferred.
+func (hr *hostRouters) match(host string) (*Router, string, bool) {
+	if len(hr.exact) == 0 && len(hr.wild) == 0 {
+		return nil, "", false
+	}
+	host = normalizeHost(host)
+	if router, ok := hr.exact[host]; ok {
+		return router, "", true
+	}
+	for _, h := range hr.wild {
+		if sub, ok := strings.CutSuffix(host, "."+h.suffix); ok && sub != "" {
+			return h.router, sub, true
+		}
+	}
+	return nil, "", false
+}
+
+func (hr *hostRouters) routers() []*Router {
+	routers := make([]*Router, 0, len(hr.exact)+len(hr.wild))
+	for _, router := range hr.exact {
+		routers = append(routers, router)
+	}
+	slices.SortFunc(routers, func(a, b *Router) int {
+		return strings.Compare(a.host, b.host)
+	})
+	for _, h := range hr.wild {
+		routers = append(routers, h.router)
+	}
+	return routers
+}
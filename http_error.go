@@ -0,0 +1,177 @@
+package golitekit
+
+import (
+	"encoding/json"
+	"errors"
+	"github/hsj/GoLiteKit/env"
+	"net/http"
+	"strings"
+)
+
+// HTTPError is an error answered with its status when returned from Serve or
+// a middleware, see ErrorHandler. Its Cause is logged but never answered, and
+// Message defaults to the status text.
+type HTTPError struct {
+	Status int
+	// Code is an application error code, such as user_not_found
+	Code    string
+	Message string
+	Details any
+	Cause   error
+}
+
+func (e *HTTPError) Error() string {
+	msg := e.Message
+	if msg == "" {
+		msg = strings.ToLower(http.StatusText(e.Status))
+	}
+	if e.Cause != nil {
+		msg += ": " + e.Cause.Error()
+	}
+	return msg
+}
+
+func (e *HTTPError) Unwrap() error {
+	return e.Cause
+}
+
+// WithCode sets the application error code of e and returns it.
+func (e *HTTPError) WithCode(code string) *HTTPError {
+	e.Code = code
+	return e
+}
+
+// WithDetails sets the details answered with e, e.g. the invalid fields, and
+// returns it.
+func (e *HTTPError) WithDetails(details any) *HTTPError {
+	e.Details = details
+	return e
+}
+
+// Wrap sets the cause of e and returns it.
+func (e *HTTPError) Wrap(cause error) *HTTPError {
+	e.Cause = cause
+	return e
+}
+
+// NewHTTPError returns an HTTPError with status, msg may be empty.
+func NewHTTPError(status int, msg string) *HTTPError {
+	return &HTTPError{Status: status, Message: msg}
+}
+
+func BadRequest(msg string) *HTTPError {
+	return NewHTTPError(http.StatusBadRequest, msg)
+}
+
+func Unauthorized(msg string) *HTTPError {
+	return NewHTTPError(http.StatusUnauthorized, msg)
+}
+
+func Forbidden(msg string) *HTTPError {
+	return NewHTTPError(http.StatusForbidden, msg)
+}
+
+func NotFound(msg string) *HTTPError {
+	return NewHTTPError(http.StatusNotFound, msg)
+}
+
+func Conflict(msg string) *HTTPError {
+	return NewHTTPError(http.StatusConflict, msg)
+}
+
+func TooManyRequests(msg string) *HTTPError {
+	return NewHTTPError(http.StatusTooManyRequests, msg)
+}
+
+func InternalServerError(msg string) *HTTPError {
+	return NewHTTPError(http.StatusInternalServerError, msg)
+}
+
+func ServiceUnavailable(msg string) *HTTPError {
+	return NewHTTPError(http.StatusServiceUnavailable, msg)
+}
+
+// AsHTTPError returns the HTTPError err is answered with: the HTTPError it
+// wraps, a 400 detailing FieldErrors, or a 500 caused by err otherwise.
+func AsHTTPError(err error) *HTTPError {
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr
+	}
+	var fieldErrs FieldErrors
+	if errors.As(err, &fieldErrs) {
+		return &HTTPError{Status: http.StatusBadRequest, Details: fieldErrs, Cause: err}
+	}
+	return &HTTPError{Status: http.StatusInternalServerError, Cause: err}
+}
+
+// ErrorHandler answers the error a request failed with, see
+// Server.ErrorHandler. It runs once the middlewares returned, instead of the
+// response buffered on the Context.
+type ErrorHandler func(w http.ResponseWriter, req *http.Request, err error)
+
+// ResponseErrorHandler answers errors as a Response, it is the default
+// ErrorHandler.
+func ResponseErrorHandler(w http.ResponseWriter, req *http.Request, err error) {
+	httpErr := AsHTTPError(err)
+	writeErrorJSON(w, "application/json", httpErr.Status, Response{
+		Status: httpErr.Status,
+		Code:   httpErr.Code,
+		Msg:    errorMessage(httpErr),
+		Data:   httpErr.Details,
+	})
+}
+
+// problem is an RFC 7807 problem details object.
+type problem struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+	Code     string `json:"code,omitempty"`
+	Errors   any    `json:"errors,omitempty"`
+}
+
+// ProblemErrorHandler answers errors as RFC 7807 application/problem+json.
+func ProblemErrorHandler(w http.ResponseWriter, req *http.Request, err error) {
+	httpErr := AsHTTPError(err)
+	p := problem{
+		Type:   "about:blank",
+		Title:  http.StatusText(httpErr.Status),
+		Status: httpErr.Status,
+		Code:   httpErr.Code,
+		Errors: httpErr.Details,
+	}
+	if msg := errorMessage(httpErr); msg != p.Title {
+		p.Detail = msg
+	}
+	if req != nil {
+		p.Instance = req.URL.Path
+	}
+	writeErrorJSON(w, "application/problem+json", httpErr.Status, p)
+}
+
+// errorMessage is the message answered for e. Outside of debug run mode the
+// message of its cause is hidden, since it may reveal internals.
+func errorMessage(e *HTTPError) string {
+	if e.Message != "" {
+		return e.Message
+	}
+	if e.Cause != nil && env.RunMode() == "debug" {
+		return e.Cause.Error()
+	}
+	return http.StatusText(e.Status)
+}
+
+func writeErrorJSON(w http.ResponseWriter, contentType string, status int, v any) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		status = http.StatusInternalServerError
+		body = []byte(`{"status":500,"msg":"Internal Server Error"}`)
+		contentType = "application/json"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(status)
+	w.Write(body)
+}
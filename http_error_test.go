@@ -0,0 +1,96 @@
+package golitekit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type failingController struct {
+	BaseController
+
+	Err error
+}
+
+func (c *failingController) Serve(ctx context.Context) error {
+	c.ServeRawData("partial")
+	return c.Err
+}
+
+func TestErrorHandler(t *testing.T) {
+	errs := map[string]error{
+		"/internal":  errors.New("dsn user:secret@db"),
+		"/not-found": NotFound("user 7 not found").WithCode("user_not_found"),
+		"/wrapped":   fmt.Errorf("load user: %w", Forbidden("").Wrap(errors.New("role guest"))),
+		"/fields":    FieldErrors{{Field: "Name", Rule: "required", Msg: "is required"}},
+	}
+
+	cases := []struct {
+		path    string
+		status  int
+		resp    string
+		problem string
+	}{
+		{"/internal", http.StatusInternalServerError,
+			`{"status":500,"msg":"Internal Server Error"}`,
+			`{"type":"about:blank","title":"Internal Server Error","status":500,"instance":"/internal"}`},
+		{"/not-found", http.StatusNotFound,
+			`{"status":404,"code":"user_not_found","msg":"user 7 not found"}`,
+			`{"type":"about:blank","title":"Not Found","status":404,"detail":"user 7 not found","instance":"/not-found","code":"user_not_found"}`},
+		{"/wrapped", http.StatusForbidden,
+			`{"status":403,"msg":"Forbidden"}`,
+			`{"type":"about:blank","title":"Forbidden","status":403,"instance":"/wrapped"}`},
+		{"/fields", http.StatusBadRequest,
+			`{"status":400,"msg":"Bad Request","data":[{"field":"Name","rule":"required","msg":"is required"}]}`,
+			`{"type":"about:blank","title":"Bad Request","status":400,"instance":"/fields","errors":[{"field":"Name","rule":"required","msg":"is required"}]}`},
+	}
+
+	s := newTestServer()
+	for path, err := range errs {
+		s.OnGet(path, &failingController{Err: err})
+	}
+	for _, handler := range []string{"response", "problem"} {
+		contentType := "application/json"
+		if handler == "problem" {
+			s.ErrorHandler(ProblemErrorHandler)
+			contentType = "application/problem+json"
+		}
+		for _, c := range cases {
+			w := httptest.NewRecorder()
+			s.ServeHTTP(w, httptest.NewRequest(http.MethodGet, c.path, nil))
+			want := c.resp
+			if handler == "problem" {
+				want = c.problem
+			}
+			if w.Code != c.status || w.Body.String() != want {
+				t.Errorf("%s %s: expected %d %s, got %d %s", handler, c.path, c.status, want, w.Code, w.Body.String())
+			}
+			if ct := w.Header().Get("Content-Type"); ct != contentType {
+				t.Errorf("%s %s: expected Content-Type %s, got %s", handler, c.path, contentType, ct)
+			}
+		}
+	}
+}
+
+func TestRateLimited(t *testing.T) {
+	s := newTestServer()
+	s.rateLimiter = NewRateLimiter(1, 1)
+	s.OnGet("/limited", &echoController{Body: "ok"})
+
+	cases := []struct {
+		status int
+		resp   string
+	}{
+		{http.StatusOK, "ok"},
+		{http.StatusTooManyRequests, `{"status":429,"msg":"Too Many Requests"}`},
+	}
+	for i, c := range cases {
+		w := serve(s, http.MethodGet, "/limited")
+		if w.Code != c.status || w.Body.String() != c.resp {
+			t.Errorf("request %d: expected %d %s, got %d %s", i, c.status, c.resp, w.Code, w.Body.String())
+		}
+	}
+}
@@ -0,0 +1,325 @@
+package golitekit
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// radixNode is a node of the compressed prefix tree the Router matches request
+// paths with. Static bytes are shared between routes through prefix, wild
+// and catch-all segments hang off the node that ends right before them.
+//
+//	/user/:id/delete
+//	/user/new
+//	/users
+//
+//	""
+//	└── "/user"
+//	    ├── "/"
+//	    │   ├── "new"          route /user/new
+//	    │   └── :id
+//	    │       └── "/delete"  route /user/:id/delete
+//	    └── "s"                route /users
+type radixNode struct {
+	prefix string
+	// static children, indices holds the first byte of each prefix
+	indices  []byte
+	children []*radixNode
+	// constrained wild children come first
+	wilds []*radixNode
+	// a catch-all hangs off the node ending before its slash, so that it
+	// also matches an empty remainder
+	catchAll *radixNode
+	route    *Route
+	// routes registered for an API version, see Version
+	versions []*Route
+
+	// name and constraint of wild and catch-all nodes, and the route that
+	// added them
+	param      string
+	constraint *paramConstraint
+	owner      *Route
+}
+
+func newRadixTree() *radixNode {
+	return &radixNode{}
+}
+
+// insert adds rt for pattern, folding the case of its static segments when
+// ignoreCase is set. It returns a *RouteError when pattern is invalid or
+// conflicts with a route added before, rt is not added then.
+func (n *radixNode) insert(pattern string, rt *Route, ignoreCase bool) error {
+	segments := splitPath(pattern)
+	node := n
+	static := ""
+
+	for i, seg := range segments {
+		w := seg.word
+		switch {
+		case isCatchAllWord(w):
+			if i != len(segments)-1 {
+				return &RouteError{route: rt, reason: "catch-all must be the last segment"}
+			}
+			node = node.insertStatic(static)
+			static = ""
+			if node.catchAll == nil {
+				node.catchAll = &radixNode{param: w[1:], owner: rt}
+			} else if node.catchAll.param != w[1:] {
+				return &RouteError{route: rt, existing: node.catchAll.owner,
+					reason: fmt.Sprintf("catch-all %s differs from %s", w, "*"+node.catchAll.param)}
+			}
+			node = node.catchAll
+		case isWildWord(w):
+			name, constraint, err := parseWildWord(w)
+			if err != nil {
+				return &RouteError{route: rt, reason: err.Error()}
+			}
+			node = node.insertStatic(static + "/")
+			static = ""
+			wild, err := node.insertWild(name, constraint, rt)
+			if err != nil {
+				return err
+			}
+			node = wild
+		default:
+			if ignoreCase {
+				w = lowerASCII(w)
+			}
+			static += "/" + w
+		}
+	}
+	node = node.insertStatic(static)
+
+	if rt.version != "" {
+		if i := node.version(rt.version); i >= 0 {
+			return &RouteError{route: rt, existing: node.versions[i], reason: "duplicate route for version " + rt.version}
+		}
+		node.versions = append(node.versions, rt)
+		return nil
+	}
+	if node.route != nil {
+		return &RouteError{route: rt, existing: node.route, reason: "duplicate route"}
+	}
+	node.route = rt
+	return nil
+}
+
+func (n *radixNode) hasRoute() bool {
+	return n.route != nil || len(n.versions) > 0
+}
+
+func (n *radixNode) version(version string) int {
+	for i, rt := range n.versions {
+		if rt.version == version {
+			return i
+		}
+	}
+	return -1
+}
+
+// pick returns the route registered for version, falling back to the route
+// registered for no version.
+func (n *radixNode) pick(version string) *Route {
+	if version != "" {
+		if i := n.version(version); i >= 0 {
+			return n.versions[i]
+		}
+	}
+	return n.route
+}
+
+// insertStatic returns the node reached from n by s, splitting edges that only
+// share a part of s.
+func (n *radixNode) insertStatic(s string) *radixNode {
+	for s != "" {
+		i := n.index(s[0])
+		if i < 0 {
+			child := &radixNode{prefix: s}
+			n.indices = append(n.indices, s[0])
+			n.children = append(n.children, child)
+			return child
+		}
+
+		child := n.children[i]
+		l := commonPrefix(child.prefix, s)
+		if l < len(child.prefix) {
+			mid := &radixNode{
+				prefix:   child.prefix[:l],
+				indices:  []byte{child.prefix[l]},
+				children: []*radixNode{child},
+			}
+			child.prefix = child.prefix[l:]
+			n.children[i] = mid
+			child = mid
+		}
+		n = child
+		s = s[l:]
+	}
+	return n
+}
+
+func (n *radixNode) insertWild(name string, constraint *paramConstraint, rt *Route) (*radixNode, error) {
+	for _, child := range n.wilds {
+		if child.constraint.String() != constraint.String() {
+			continue
+		}
+		if child.param != name {
+			return nil, &RouteError{route: rt, existing: child.owner,
+				reason: fmt.Sprintf("param :%s differs from :%s at the same position", name, child.param)}
+		}
+		return child, nil
+	}
+
+	child := &radixNode{param: name, constraint: constraint, owner: rt}
+	i := len(n.wilds)
+	if constraint != nil {
+		for i > 0 && n.wilds[i-1].constraint == nil {
+			i--
+		}
+	}
+	n.wilds = append(n.wilds, nil)
+	copy(n.wilds[i+1:], n.wilds[i:])
+	n.wilds[i] = child
+	return child, nil
+}
+
+func (n *radixNode) index(c byte) int {
+	for i, b := range n.indices {
+		if b == c {
+			return i
+		}
+	}
+	return -1
+}
+
+func commonPrefix(a, b string) int {
+	i := 0
+	for i < len(a) && i < len(b) && a[i] == b[i] {
+		i++
+	}
+	return i
+}
+
+// lookup matches path from offset i, right after the prefix of n. key is path
+// with its case folded when the router ignores case, both have the same
+// length so params are cut from path. Static children are preferred over wild
+// ones and wild ones over catch-all, when a branch fails to match the whole
+// path the next one is tried and the params it captured are dropped.
+func (n *radixNode) lookup(path, key string, i int, params *Params) *radixNode {
+	if i == len(key) {
+		if n.hasRoute() {
+			return n
+		}
+		if n.catchAll != nil {
+			*params = append(*params, Param{Key: n.catchAll.param})
+			return n.catchAll
+		}
+		return nil
+	}
+
+	if c := n.index(key[i]); c >= 0 {
+		child := n.children[c]
+		if strings.HasPrefix(key[i:], child.prefix) {
+			if found := child.lookup(path, key, i+len(child.prefix), params); found != nil {
+				return found
+			}
+		}
+	}
+
+	if len(n.wilds) > 0 {
+		end := strings.IndexByte(key[i:], '/')
+		if end < 0 {
+			end = len(key)
+		} else {
+			end += i
+		}
+		if end > i {
+			value := path[i:end]
+			for _, wild := range n.wilds {
+				if wild.constraint != nil && !wild.constraint.match(value) {
+					continue
+				}
+				l := len(*params)
+				*params = append(*params, Param{Key: wild.param, Value: value})
+				if found := wild.lookup(path, key, end, params); found != nil {
+					return found
+				}
+				*params = (*params)[:l]
+			}
+		}
+	}
+
+	if n.catchAll != nil && key[i] == '/' {
+		*params = append(*params, Param{Key: n.catchAll.param, Value: path[i+1:]})
+		return n.catchAll
+	}
+
+	return nil
+}
+
+func (n *radixNode) clone() *radixNode {
+	cloned := *n
+	cloned.indices = slices.Clone(n.indices)
+	cloned.versions = slices.Clone(n.versions)
+	cloned.children = make([]*radixNode, len(n.children))
+	for i, child := range n.children {
+		cloned.children[i] = child.clone()
+	}
+	cloned.wilds = make([]*radixNode, len(n.wilds))
+	for i, wild := range n.wilds {
+		cloned.wilds[i] = wild.clone()
+	}
+	if n.catchAll != nil {
+		cloned.catchAll = n.catchAll.clone()
+	}
+	return &cloned
+}
+
+type segment struct {
+	word  string
+	start int
+}
+
+func isWildWord(word string) bool {
+	return strings.HasPrefix(word, ":")
+}
+
+func isCatchAllWord(word string) bool {
+	return strings.HasPrefix(word, "*")
+}
+
+// splitPath splits path into its non-empty segments and remembers where each
+// one starts, so that a catch-all can capture the rest of the path verbatim.
+func splitPath(path string) []segment {
+	segments := make([]segment, 0, strings.Count(path, "/")+1)
+	start := -1
+	for i := 0; i <= len(path); i++ {
+		if i == len(path) || path[i] == '/' {
+			if start >= 0 {
+				segments = append(segments, segment{word: path[start:i], start: start})
+				start = -1
+			}
+			continue
+		}
+		if start < 0 {
+			start = i
+		}
+	}
+	return segments
+}
+
+func lowerASCII(s string) string {
+	for i := 0; i < len(s); i++ {
+		if 'A' <= s[i] && s[i] <= 'Z' {
+			b := []byte(s)
+			for j := i; j < len(b); j++ {
+				if 'A' <= b[j] && b[j] <= 'Z' {
+					b[j] += 'a' - 'A'
+				}
+			}
+			return string(b)
+		}
+	}
+	return s
+}
@@ -0,0 +1,296 @@
+package golitekit
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+type TestController struct {
+	BaseController
+
+	value string
+}
+
+var radixRoutes = []string{
+	"/",
+	"/user/profile/edit",
+	"/user/:id/delete",
+	"/user/:id",
+	"/user/new",
+	"/users",
+	"/user/progress",
+	"/files/*filepath",
+	"/files/static/logo.png",
+	"/files/:dir/index",
+	"/filesystem",
+	"/a/:b/c/d",
+	"/a/:b/:c/e",
+	"/a/*rest",
+	"/item/:id<int>",
+	"/item/:name",
+	"/deep/:x/:y/:z",
+}
+
+func TestRadixMatch(t *testing.T) {
+	r := NewRouter()
+	for _, p := range radixRoutes {
+		r.OnGet(p, &TestController{value: p})
+	}
+
+	cases := []struct {
+		path   string
+		route  string
+		params Params
+	}{
+		{"/", "/", nil},
+		{"", "/", nil},
+		{"/user/profile/edit", "/user/profile/edit", nil},
+		{"/user/profile/delete", "/user/:id/delete", Params{{"id", "profile"}}},
+		{"/user/profile", "/user/:id", Params{{"id", "profile"}}},
+		{"/user/pro", "/user/:id", Params{{"id", "pro"}}},
+		{"/user/progress", "/user/progress", nil},
+		{"/user/new", "/user/new", nil},
+		{"/user/newbie", "/user/:id", Params{{"id", "newbie"}}},
+		{"/user/new/delete", "/user/:id/delete", Params{{"id", "new"}}},
+		{"/user/1/delete/", "/user/:id/delete", Params{{"id", "1"}}},
+		{"/user/123/delete/", "/user/:id/delete", Params{{"id", "123"}}},
+		{"/users", "/users", nil},
+		{"/files", "/files/*filepath", Params{{"filepath", ""}}},
+		{"/files/", "/files/*filepath", Params{{"filepath", ""}}},
+		{"/files/a.txt", "/files/*filepath", Params{{"filepath", "a.txt"}}},
+		{"/files/static/logo.png", "/files/static/logo.png", nil},
+		{"/files/static/other.png", "/files/*filepath", Params{{"filepath", "static/other.png"}}},
+		{"/files/static/index", "/files/:dir/index", Params{{"dir", "static"}}},
+		{"/files/docs/index/more", "/files/*filepath", Params{{"filepath", "docs/index/more"}}},
+		{"/files/a//b/", "/files/*filepath", Params{{"filepath", "a//b"}}},
+		{"/filesystem", "/filesystem", nil},
+		{"/a/1/c/d", "/a/:b/c/d", Params{{"b", "1"}}},
+		{"/a/1/c/e", "/a/:b/:c/e", Params{{"b", "1"}, {"c", "c"}}},
+		{"/a/1/x/e", "/a/:b/:c/e", Params{{"b", "1"}, {"c", "x"}}},
+		{"/a/1/c/f", "/a/*rest", Params{{"rest", "1/c/f"}}},
+		{"/a", "/a/*rest", Params{{"rest", ""}}},
+		{"/item/42", "/item/:id<int>", Params{{"id", "42"}}},
+		{"/item/apple", "/item/:name", Params{{"name", "apple"}}},
+		{"/deep/1/2/3", "/deep/:x/:y/:z", Params{{"x", "1"}, {"y", "2"}, {"z", "3"}}},
+		{"/user", "", nil},
+		{"/user/1/2", "", nil},
+		{"/user/1/delete/now", "", nil},
+		{"/filesX", "", nil},
+		{"/fil", "", nil},
+		{"/deep/1/2", "", nil},
+		{"/deep/1/2/3/4", "", nil},
+		{"/unknown", "", nil},
+		{"/unknown/user/new", "", nil},
+	}
+
+	for _, c := range cases {
+		rt, params, ok := r.Route(http.MethodGet, c.path)
+		if c.route == "" {
+			if ok {
+				t.Errorf("path %s: expected no match, got %s", c.path, rt.path)
+			}
+			continue
+		}
+		if !ok {
+			t.Errorf("path %s: expected %s, got no match", c.path, c.route)
+			continue
+		}
+		if rt.controller.(*TestController).value != c.route {
+			t.Errorf("path %s: expected %s, got %s", c.path, c.route, rt.path)
+			continue
+		}
+		if len(params) != len(c.params) {
+			t.Errorf("path %s: expected params %v, got %v", c.path, c.params, params)
+			continue
+		}
+		for i := range params {
+			if params[i] != c.params[i] {
+				t.Errorf("path %s: expected params %v, got %v", c.path, c.params, params)
+				break
+			}
+		}
+	}
+}
+
+func TestRadixConflict(t *testing.T) {
+	cases := []struct {
+		name     string
+		paths    []string
+		conflict bool
+	}{
+		{"catch-all not last", []string{"/files/*filepath/name"}, false},
+		{"renamed catch-all", []string{"/files/*filepath", "/files/*name"}, true},
+		{"renamed wild", []string{"/user/:id/a", "/user/:name/b"}, true},
+		{"duplicate static", []string{"/user/profile", "/user/profile/"}, true},
+		{"duplicate wild", []string{"/user/:id", "/user/:id"}, true},
+		{"duplicate catch-all", []string{"/files/*filepath", "/files/*filepath"}, true},
+		{"renamed constrained wild", []string{"/item/:id<int>", "/item/:no<int>/name"}, true},
+		{"invalid constraint", []string{"/user/:id<int"}, false},
+		{"empty constraint", []string{"/user/:id<>"}, false},
+		{"invalid pattern", []string{"/user/:id<[a-z>"}, false},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			r := NewRouter()
+			for _, p := range c.paths {
+				r.OnGet(p, &TestController{})
+			}
+			errs := r.Validate()
+			if len(errs) != 1 {
+				t.Fatalf("Expected 1 error for paths %v, got %v", c.paths, errs)
+			}
+			msg := errs[0].Error()
+			if n := strings.Count(msg, "radix_test.go:"); c.conflict && n != 2 || !c.conflict && n != 1 {
+				t.Errorf("Expected the registration sites in %q", msg)
+			}
+			if len(r.Routes()) != len(c.paths)-1 {
+				t.Errorf("Expected the conflicting route not to be registered, got %v", r.Routes())
+			}
+		})
+	}
+
+	// every conflict is reported, the first route registered wins
+	r := NewRouter()
+	r.OnGet("/a", &TestController{})
+	r.OnGet("/a", &TestController{})
+	r.OnGet("/b/:id", &TestController{})
+	r.OnGet("/b/:name", &TestController{})
+	if errs := r.Validate(); len(errs) != 2 {
+		t.Errorf("Expected 2 errors, got %v", errs)
+	}
+	if rt, _, ok := r.Route(http.MethodGet, "/b/1"); !ok || rt.path != "/b/:id" {
+		t.Errorf("Expected /b/:id to be served")
+	}
+}
+
+func TestRadixConstraint(t *testing.T) {
+	routes := []string{
+		"/item/:id<int>",
+		"/item/:name",
+		"/post/:slug<[a-z0-9-]+>",
+		"/v/:uuid<uuid>",
+		"/v/:id<uint>/edit",
+		"/v/:code<alpha>/edit",
+		"/price/:value<float>",
+	}
+	r := NewRouter()
+	for _, p := range routes {
+		r.OnGet(p, &TestController{})
+	}
+
+	cases := []struct {
+		path  string
+		route string
+		key   string
+		value string
+	}{
+		{"/item/42", "/item/:id<int>", "id", "42"},
+		{"/item/-42", "/item/:id<int>", "id", "-42"},
+		{"/item/apple", "/item/:name", "name", "apple"},
+		{"/post/hello-world-2", "/post/:slug<[a-z0-9-]+>", "slug", "hello-world-2"},
+		{"/post/Hello", "", "", ""},
+		{"/post/a.b", "", "", ""},
+		{"/v/123e4567-e89b-12d3-a456-426614174000", "/v/:uuid<uuid>", "uuid", "123e4567-e89b-12d3-a456-426614174000"},
+		{"/v/123e4567", "", "", ""},
+		{"/v/7/edit", "/v/:id<uint>/edit", "id", "7"},
+		{"/v/abc/edit", "/v/:code<alpha>/edit", "code", "abc"},
+		{"/v/-7/edit", "", "", ""},
+		{"/price/1.5", "/price/:value<float>", "value", "1.5"},
+		{"/price/cheap", "", "", ""},
+	}
+	for _, c := range cases {
+		rt, params, ok := r.Route(http.MethodGet, c.path)
+		if c.route == "" {
+			if ok {
+				t.Errorf("path %s: expected no match, got %s", c.path, rt.path)
+			}
+			continue
+		}
+		if !ok || rt.path != c.route {
+			t.Errorf("path %s: expected %s, got %v", c.path, c.route, rt)
+			continue
+		}
+		if params.Get(c.key) != c.value {
+			t.Errorf("path %s: expected %s = %s, got %v", c.path, c.key, c.value, params)
+		}
+	}
+}
+
+func TestRadixAllocs(t *testing.T) {
+	r := NewRouter()
+	for _, p := range radixRoutes {
+		r.OnGet(p, &TestController{})
+	}
+
+	params := make(Params, 0, 8)
+	allocs := testing.AllocsPerRun(100, func() {
+		params = params[:0]
+		if _, ok := r.find(http.MethodGet, "/deep/1/2/3", "", &params); !ok {
+			t.Fatal("route not found")
+		}
+	})
+	if allocs != 0 {
+		t.Errorf("expected no allocations, got %v", allocs)
+	}
+}
+
+var benchPaths = []string{
+	"/",
+	"/user/profile/edit",
+	"/user/42/delete",
+	"/files/static/css/main.css",
+	"/a/1/c/e",
+	"/item/42",
+	"/deep/1/2/3",
+}
+
+func BenchmarkTrieGet(b *testing.B) {
+	static := make(map[string]Controller)
+	trie := NewTrie()
+	for _, p := range radixRoutes {
+		if isStaticPattern(p) {
+			static[dealSlash(p)] = &TestController{}
+		} else {
+			trie.Add(p, &TestController{})
+		}
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		path := dealSlash(benchPaths[i%len(benchPaths)])
+		if _, ok := static[path]; ok {
+			continue
+		}
+		if _, _, ok := trie.Get(path); !ok {
+			b.Fatalf("route not found: %s", path)
+		}
+	}
+}
+
+func BenchmarkRadixFind(b *testing.B) {
+	r := NewRouter()
+	for _, p := range radixRoutes {
+		r.OnGet(p, &TestController{})
+	}
+	params := make(Params, 0, 8)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		params = params[:0]
+		if _, ok := r.find(http.MethodGet, benchPaths[i%len(benchPaths)], "", &params); !ok {
+			b.Fatalf("route not found: %s", benchPaths[i%len(benchPaths)])
+		}
+	}
+}
+
+func isStaticPattern(p string) bool {
+	for i := 0; i < len(p); i++ {
+		if p[i] == ':' || p[i] == '*' {
+			return false
+		}
+	}
+	return true
+}
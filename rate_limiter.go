@@ -2,14 +2,14 @@ package golitekit
 
 import (
 	"context"
-	"errors"
 	"github/hsj/GoLiteKit/logger"
 
 	"golang.org/x/time/rate"
 )
 
+// ErrRateLimited is answered with a 429 when the RateLimiter rejects a request.
 var (
-	ErrRateLimited = errors.New("rate limit")
+	ErrRateLimited error = TooManyRequests("")
 )
 
 type RateLimiter struct {
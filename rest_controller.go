@@ -1,11 +1,17 @@
 package golitekit
 
+import (
+	"context"
+	"net/http"
+)
+
 const (
 	OK = 0
 )
 
 type Response struct {
 	Status int    `json:"status"`
+	Code   string `json:"code,omitempty"`
 	Msg    string `json:"msg,omitempty"`
 	Data   any    `json:"data,omitempty"`
 }
@@ -22,3 +28,45 @@ func (c *RestController) ServeData(data any) {
 	}
 	c.BaseController.ServeJSON(res)
 }
+
+func (c *RestController) ServeError(status int, msg string) {
+	res := Response{
+		Status: status,
+		Msg:    msg,
+	}
+	c.BaseController.ServeJSON(res)
+}
+
+// NotFoundController answers requests whose path matches no route, see
+// Server.NotFound.
+type NotFoundController struct {
+	RestController
+}
+
+func (c *NotFoundController) Serve(ctx context.Context) error {
+	c.ServeError(http.StatusNotFound, http.StatusText(http.StatusNotFound))
+	return nil
+}
+
+// MethodNotAllowedController answers requests whose path matches routes of
+// other methods only, see Server.MethodNotAllowed.
+type MethodNotAllowedController struct {
+	RestController
+}
+
+func (c *MethodNotAllowedController) Serve(ctx context.Context) error {
+	c.ServeError(http.StatusMethodNotAllowed, http.StatusText(http.StatusMethodNotAllowed))
+	return nil
+}
+
+// RoutesController serves the route table, see Server.ExposeRoutes.
+type RoutesController struct {
+	RestController
+
+	Routes func() []RouteInfo
+}
+
+func (c *RoutesController) Serve(ctx context.Context) error {
+	c.ServeData(c.Routes())
+	return nil
+}
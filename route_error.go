@@ -0,0 +1,75 @@
+package golitekit
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+)
+
+var pkgPath = reflect.TypeOf(Router{}).PkgPath()
+
+// RouteError reports a route that could not be registered, either because its
+// pattern is invalid or because it conflicts with a route registered before.
+type RouteError struct {
+	route    *Route
+	existing *Route
+	reason   string
+}
+
+func (e *RouteError) Error() string {
+	if e.existing == nil {
+		return fmt.Sprintf("%s %s (%s): %s", e.route.method, e.route.path, e.route.site, e.reason)
+	}
+	return fmt.Sprintf("%s %s (%s) conflicts with %s %s (%s): %s",
+		e.route.method, e.route.path, e.route.site,
+		e.existing.method, e.existing.path, e.existing.site, e.reason)
+}
+
+// callerSite returns the file and line of the first caller outside this
+// package, that is where a route is registered.
+func callerSite() string {
+	pcs := make([]uintptr, 16)
+	n := runtime.Callers(3, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	for {
+		f, more := frames.Next()
+		if !strings.HasPrefix(f.Function, pkgPath+".") || strings.HasSuffix(f.File, "_test.go") {
+			return fmt.Sprintf("%s:%d", f.File, f.Line)
+		}
+		if !more {
+			return "unknown"
+		}
+	}
+}
+
+// Validate returns the errors of every route that could not be registered,
+// and of routes shadowing files served by Static.
+func (r *Router) Validate() []error {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	errs := append([]error{}, r.errs...)
+	for _, mount := range r.routes {
+		sc, ok := mount.controller.(*StaticController)
+		if !mount.static || !ok {
+			continue
+		}
+		prefix := strings.TrimSuffix(mount.path, "/*"+StaticPathParam)
+		for _, rt := range r.routes {
+			if rt.static || rt.method != mount.method || strings.ContainsAny(rt.path, ":*") {
+				continue
+			}
+			rel, ok := strings.CutPrefix(rt.path, prefix+"/")
+			if !ok {
+				continue
+			}
+			if _, err := os.Stat(filepath.Join(sc.Path, filepath.FromSlash(rel))); err == nil {
+				errs = append(errs, &RouteError{route: rt, existing: mount, reason: "shadows a file served by Static"})
+			}
+		}
+	}
+	return errs
+}
@@ -0,0 +1,100 @@
+package golitekit
+
+import (
+	"maps"
+	"mime"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// RouteOption configures a route when it is registered, see Meta.
+type RouteOption interface {
+	applyRoute(rt *Route)
+}
+
+// Meta is data attached to a route at registration, such as required scopes,
+// an audit category or a cache policy. Middlewares and controllers read it
+// with Context.RouteMeta, several Meta given to one route are merged.
+type Meta map[string]any
+
+func (m Meta) applyRoute(rt *Route) {
+	if len(m) == 0 {
+		return
+	}
+	if rt.meta == nil {
+		rt.meta = make(Meta, len(m))
+	}
+	maps.Copy(rt.meta, m)
+}
+
+// Get returns the value of key, or nil if there is none.
+func (m Meta) Get(key string) any {
+	return m[key]
+}
+
+// String returns the value of key when it is a string.
+func (m Meta) String(key string) string {
+	s, _ := m[key].(string)
+	return s
+}
+
+// Use runs middlewares for one route only, after the server-wide and group
+// middlewares, e.g. s.OnPost("/admin/users", c, golitekit.Use(auth)).
+func Use(middlewares ...Middleware) RouteOption {
+	return routeMiddlewares(middlewares)
+}
+
+type routeMiddlewares []Middleware
+
+func (m routeMiddlewares) applyRoute(rt *Route) {
+	// clipped, since Replace copies routes sharing their middlewares
+	rt.middlewares = append(slices.Clip(rt.middlewares), m...)
+}
+
+// VersionParam is the Accept media type parameter and the query parameter a
+// request can ask for an API version with, after the Accept-Version header.
+const VersionParam = "version"
+
+// Version registers a route for an API version, so that several versions of a
+// path coexist. A request is served by the route of the version it asks for,
+// see VersionParam, or of the configured default version, and else by the
+// route registered for no version. A leading "v" is ignored, v2 and 2 are the
+// same version.
+type Version string
+
+func (v Version) applyRoute(rt *Route) {
+	rt.version = normalizeVersion(string(v))
+}
+
+func normalizeVersion(v string) string {
+	v = strings.TrimSpace(v)
+	if len(v) > 1 && (v[0] == 'v' || v[0] == 'V') {
+		v = v[1:]
+	}
+	return v
+}
+
+// requestVersion returns the API version req asks for, from the
+// Accept-Version header, a version parameter of an Accept media type such as
+// application/vnd.app+json; version=2, or a version query parameter.
+func requestVersion(req *http.Request) string {
+	if v := req.Header.Get("Accept-Version"); v != "" {
+		return v
+	}
+	for _, accept := range req.Header.Values("Accept") {
+		if !strings.Contains(accept, VersionParam) {
+			continue
+		}
+		for _, mediaRange := range strings.Split(accept, ",") {
+			_, params, err := mime.ParseMediaType(mediaRange)
+			if err == nil && params[VersionParam] != "" {
+				return params[VersionParam]
+			}
+		}
+	}
+	if strings.Contains(req.URL.RawQuery, VersionParam) {
+		return req.URL.Query().Get(VersionParam)
+	}
+	return ""
+}
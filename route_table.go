@@ -0,0 +1,77 @@
+package golitekit
+
+// routeTable holds the radix trees requests are matched against. A published
+// table is never modified: changes are made to a copy, the draft, which
+// replaces it on the next lookup, so routes can change while serving.
+type routeTable struct {
+	// method -> radix tree
+	trees map[string]*radixNode
+}
+
+func newRouteTable() *routeTable {
+	return &routeTable{
+		trees: make(map[string]*radixNode, 4),
+	}
+}
+
+func (t *routeTable) clone() *routeTable {
+	cloned := newRouteTable()
+	if t == nil {
+		return cloned
+	}
+	for method, tree := range t.trees {
+		cloned.trees[method] = tree.clone()
+	}
+	return cloned
+}
+
+func (t *routeTable) insert(rt *Route, ignoreCase bool) error {
+	tree, ok := t.trees[rt.method]
+	if !ok {
+		tree = newRadixTree()
+		t.trees[rt.method] = tree
+	}
+	return tree.insert(rt.path, rt, ignoreCase)
+}
+
+// current returns the published table, publishing the draft first if there is
+// one.
+func (r *Router) current() *routeTable {
+	if r.dirty.Load() {
+		r.mu.Lock()
+		if r.draft != nil {
+			r.table.Store(r.draft)
+			r.draft = nil
+		}
+		r.dirty.Store(false)
+		r.mu.Unlock()
+	}
+	return r.table.Load()
+}
+
+// edit returns the draft, copying the published table when there is none yet.
+// It must be called with r.mu held.
+func (r *Router) edit() *routeTable {
+	if r.draft == nil {
+		r.draft = r.table.Load().clone()
+	}
+	r.dirty.Store(true)
+	return r.draft
+}
+
+// rebuild replaces the draft with a table of routes, which were all inserted
+// before. It must be called with r.mu held.
+func (r *Router) rebuild(routes []*Route) {
+	table := newRouteTable()
+	names := make(map[string]*Route)
+	for _, rt := range routes {
+		_ = table.insert(rt, r.ignoreCase)
+		if rt.name != "" {
+			names[rt.name] = rt
+		}
+	}
+	r.draft = table
+	r.names = names
+	r.routes = routes
+	r.dirty.Store(true)
+}
@@ -1,92 +1,548 @@
 package golitekit
 
 import (
+	"fmt"
+	"maps"
 	"net/http"
+	"net/url"
+	"path"
+	"reflect"
+	"runtime"
+	"slices"
 	"strings"
+	"sync"
+	"sync/atomic"
 )
 
+// Route is a registered method and path pattern. It is returned by the
+// registration methods so that it can be configured further, e.g. named.
+type Route struct {
+	method      string
+	path        string
+	name        string
+	controller  Controller
+	middlewares MiddlewareQueue
+	// static routes are registered by Router.Static
+	static bool
+	// site is the file:line the route is registered at
+	site    string
+	meta    Meta
+	version string
+
+	router *Router
+}
+
+// Routes are the routes registered at once by OnAny.
+type Routes []*Route
+
+// Param is a router param captured from the request path.
+type Param struct {
+	Key   string
+	Value string
+}
+
+// Params are the router params of a request in path order. They are backed by
+// a slice reused across requests, so matching a route does not allocate.
+type Params []Param
+
+// Lookup returns the value of the first param named key.
+func (ps Params) Lookup(key string) (string, bool) {
+	for _, p := range ps {
+		if p.Key == key {
+			return p.Value, true
+		}
+	}
+	return "", false
+}
+
+// Get returns the value of the first param named key, or "" if there is none.
+func (ps Params) Get(key string) string {
+	v, _ := ps.Lookup(key)
+	return v
+}
+
 type Router struct {
-	// method -> path -> controller
-	static      map[string]Controller
-	routers     map[string]map[string]Controller
-	wildRouters map[string]*Trie
+	// mu guards everything but table, which is swapped atomically
+	mu    sync.RWMutex
+	table atomic.Pointer[routeTable]
+	// draft holds the changes not published to table yet
+	draft *routeTable
+	dirty atomic.Bool
+
+	// name -> route
+	names map[string]*Route
+	// in registration order
+	routes []*Route
+	// registration errors, see Validate
+	errs []error
+
+	ignoreCase bool
+	// host is the pattern the router is registered for, see Server.Host
+	host string
+}
+
+// RouteInfo describes a registered route, see Server.Routes.
+type RouteInfo struct {
+	Host        string   `json:"host,omitempty"`
+	Method      string   `json:"method"`
+	Pattern     string   `json:"pattern"`
+	Name        string   `json:"name,omitempty"`
+	Version     string   `json:"version,omitempty"`
+	Controller  string   `json:"controller"`
+	Middlewares []string `json:"middlewares,omitempty"`
+	Meta        Meta     `json:"meta,omitempty"`
 }
 
 func NewRouter() Router {
 	return Router{
-		static:      make(map[string]Controller),
-		routers:     make(map[string]map[string]Controller, 4),
-		wildRouters: make(map[string]*Trie, 4),
+		names: make(map[string]*Route),
 	}
 }
 
-func (r *Router) OnPost(path string, controller Controller) {
-	path = dealSlash(path)
-	r.register(http.MethodPost, path, controller)
+const (
+	// MountPathParam is the router param capturing the path below the prefix
+	// of a mounted handler
+	MountPathParam = "mountpath"
+)
+
+var anyMethods = []string{
+	http.MethodGet,
+	http.MethodHead,
+	http.MethodPost,
+	http.MethodPut,
+	http.MethodPatch,
+	http.MethodDelete,
+	http.MethodOptions,
+	http.MethodConnect,
+	http.MethodTrace,
 }
 
-func (r *Router) OnGet(path string, controller Controller) {
-	path = dealSlash(path)
-	r.register(http.MethodGet, path, controller)
+// IgnoreCase makes static path segments match case-insensitively, it must be
+// called before any route is registered.
+func (r *Router) IgnoreCase(ignore bool) {
+	r.ignoreCase = ignore
 }
 
-func (r *Router) OnPut(path string, controller Controller) {
-	path = dealSlash(path)
-	r.register(http.MethodPut, path, controller)
+// Handle registers controller for method and path, the options attach
+// metadata, a version or middlewares to the route, see Meta, Version and Use.
+func (r *Router) Handle(method, path string, controller Controller, opts ...RouteOption) *Route {
+	return r.handle(method, path, controller, nil, opts)
 }
 
-func (r *Router) OnDelete(path string, controller Controller) {
+func (r *Router) handle(method, path string, controller Controller, middlewares MiddlewareQueue, opts []RouteOption) *Route {
 	path = dealSlash(path)
-	r.register(http.MethodDelete, path, controller)
+	return r.register(strings.ToUpper(method), path, controller, middlewares, opts)
 }
 
-func (r *Router) Static(path string, controller Controller) {
-	path = dealSlash(path)
-	r.static[path] = controller
+func (r *Router) OnGet(path string, controller Controller, opts ...RouteOption) *Route {
+	return r.Handle(http.MethodGet, path, controller, opts...)
+}
+
+func (r *Router) OnHead(path string, controller Controller, opts ...RouteOption) *Route {
+	return r.Handle(http.MethodHead, path, controller, opts...)
+}
+
+func (r *Router) OnPost(path string, controller Controller, opts ...RouteOption) *Route {
+	return r.Handle(http.MethodPost, path, controller, opts...)
+}
+
+func (r *Router) OnPut(path string, controller Controller, opts ...RouteOption) *Route {
+	return r.Handle(http.MethodPut, path, controller, opts...)
+}
+
+func (r *Router) OnPatch(path string, controller Controller, opts ...RouteOption) *Route {
+	return r.Handle(http.MethodPatch, path, controller, opts...)
 }
 
-func (r *Router) register(method, path string, controller Controller) {
-	if strings.Contains(path, ":") {
-		if _, ok := r.wildRouters[method]; !ok {
-			r.wildRouters[method] = NewTrie()
+func (r *Router) OnDelete(path string, controller Controller, opts ...RouteOption) *Route {
+	return r.Handle(http.MethodDelete, path, controller, opts...)
+}
+
+func (r *Router) OnOptions(path string, controller Controller, opts ...RouteOption) *Route {
+	return r.Handle(http.MethodOptions, path, controller, opts...)
+}
+
+// OnAny registers controller for every standard HTTP method.
+func (r *Router) OnAny(path string, controller Controller, opts ...RouteOption) Routes {
+	return r.onAny(path, controller, nil, opts)
+}
+
+func (r *Router) onAny(path string, controller Controller, middlewares MiddlewareQueue, opts []RouteOption) Routes {
+	routes := make(Routes, 0, len(anyMethods))
+	for _, method := range anyMethods {
+		routes = append(routes, r.handle(method, path, controller, middlewares, opts))
+	}
+	return routes
+}
+
+// Static serves every GET request below path with controller, the remainder
+// of the request path is captured as the StaticPathParam router param.
+func (r *Router) Static(path string, controller Controller) *Route {
+	path = joinPath(path, "/*"+StaticPathParam)
+	rt := r.newRoute(http.MethodGet, path, controller, nil)
+	rt.static = true
+	r.insert(rt)
+	return rt
+}
+
+// HandleFunc registers a net/http handler function, see HandlerController.
+func (r *Router) HandleFunc(method, path string, handler http.HandlerFunc, opts ...RouteOption) *Route {
+	return r.handle(method, path, &HandlerController{Handler: handler}, nil, opts)
+}
+
+// Mount serves every method and every path below prefix with a net/http
+// handler, see HandlerController. The handler sees the full request path,
+// wrap it with http.StripPrefix to serve it relative to prefix.
+func (r *Router) Mount(prefix string, handler http.Handler, opts ...RouteOption) Routes {
+	return r.OnAny(joinPath(prefix, "/*"+MountPathParam), &HandlerController{Handler: handler}, opts...)
+}
+
+// MountRouter registers every route of other below prefix, keeping their
+// names, metadata and middlewares, the given middlewares run before the
+// latter.
+func (r *Router) MountRouter(prefix string, other *Router, middlewares ...Middleware) Routes {
+	other.mu.RLock()
+	srcs := slices.Clone(other.routes)
+	other.mu.RUnlock()
+
+	routes := make(Routes, 0, len(srcs))
+	for _, src := range srcs {
+		var rt *Route
+		if src.static {
+			rt = r.Static(joinPath(prefix, strings.TrimSuffix(src.path, "/*"+StaticPathParam)), src.controller)
+		} else {
+			mws := NewMiddlewareQueue(middlewares...).Clone()
+			mws.Use(src.middlewares...)
+			rt = r.handle(src.method, joinPath(prefix, src.path), src.controller, mws, []RouteOption{src.meta, Version(src.version)})
 		}
-		r.wildRouters[method].Add(path, controller)
-	} else {
-		if _, ok := r.routers[method]; !ok {
-			r.routers[method] = make(map[string]Controller)
+		if src.name != "" {
+			rt.Name(src.name)
 		}
-		r.routers[method][path] = controller
+		routes = append(routes, rt)
 	}
+	return routes
+}
+
+func (r *Router) newRoute(method, path string, controller Controller, middlewares MiddlewareQueue) *Route {
+	return &Route{
+		method:      method,
+		path:        path,
+		controller:  controller,
+		middlewares: middlewares.Clone(),
+		site:        callerSite(),
+		router:      r,
+	}
+}
+
+func (r *Router) register(method, path string, controller Controller, middlewares MiddlewareQueue, opts []RouteOption) *Route {
+	rt := r.newRoute(method, path, controller, middlewares)
+	for _, opt := range opts {
+		opt.applyRoute(rt)
+	}
+	r.insert(rt)
+	return rt
+}
+
+// insert adds rt to the draft, a route that cannot be added is recorded for
+// Validate instead.
+func (r *Router) insert(rt *Route) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.insertLocked(rt)
+}
+
+func (r *Router) insertLocked(rt *Route) {
+	if err := r.edit().insert(rt, r.ignoreCase); err != nil {
+		r.errs = append(r.errs, err)
+		return
+	}
+	r.routes = append(r.routes, rt)
+}
+
+// Replace swaps the controller of the route registered for method and path,
+// keeping its name, metadata and middlewares, or registers it when there is
+// none. The options are applied to the route, a Version option selects the
+// version replaced and Use adds middlewares after the kept ones. It is safe
+// to call while serving, requests already routed keep the old controller.
+func (r *Router) Replace(method, path string, controller Controller, opts ...RouteOption) *Route {
+	method, path = strings.ToUpper(method), dealSlash(path)
+	rt := r.newRoute(method, path, controller, nil)
+	for _, opt := range opts {
+		opt.applyRoute(rt)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	routes := slices.Clone(r.routes)
+	i := slices.IndexFunc(routes, func(other *Route) bool {
+		return other.method == method && other.path == path && other.version == rt.version
+	})
+	if i < 0 {
+		r.insertLocked(rt)
+		return rt
+	}
+
+	replaced := *routes[i]
+	replaced.controller = controller
+	replaced.meta = maps.Clone(replaced.meta)
+	for _, opt := range opts {
+		opt.applyRoute(&replaced)
+	}
+	routes[i] = &replaced
+	r.rebuild(routes)
+	return routes[i]
+}
+
+// Remove unregisters the routes registered for method and path, of every
+// version, it reports whether there was one. It is safe to call while serving.
+func (r *Router) Remove(method, path string) bool {
+	method, path = strings.ToUpper(method), dealSlash(path)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	routes := slices.DeleteFunc(slices.Clone(r.routes), func(rt *Route) bool {
+		return rt.method == method && rt.path == path
+	})
+	if len(routes) == len(r.routes) {
+		return false
+	}
+	r.rebuild(routes)
+	return true
+}
+
+// cleanPath returns the canonical form of a request path: rooted, without
+// empty, "." or ".." segments and without a trailing slash.
+func cleanPath(p string) string {
+	if p == "" {
+		return "/"
+	}
+	if p[0] != '/' {
+		p = "/" + p
+	}
+	return path.Clean(p)
 }
 
 func dealSlash(path string) string {
 	if !strings.HasPrefix(path, "/") {
-		return "/" + path
+		path = "/" + path
 	}
-	path = strings.TrimRight(path, "/")
-	return path
+	return strings.TrimRight(path, "/")
+}
+
+// Route returns the route registered for method and path together with the
+// router params captured from path. HEAD requests fall back to the GET route
+// of the same path.
+func (r *Router) Route(method, path string) (*Route, Params, bool) {
+	return r.RouteVersion(method, path, "")
+}
+
+// RouteVersion is Route for a request asking for an API version, it returns
+// the route registered for version, or else the one registered for no
+// version, see Version.
+func (r *Router) RouteVersion(method, path, version string) (*Route, Params, bool) {
+	var params Params
+	rt, ok := r.find(method, path, version, &params)
+	return rt, params, ok
 }
 
-func (r *Router) Route(method, path string) (Controller, map[string]string, bool) {
+// find is RouteVersion appending the router params to params, it does not
+// allocate when params has enough capacity.
+func (r *Router) find(method, path, version string, params *Params) (*Route, bool) {
+	n := len(*params)
+	if found := r.lookup(method, path, params); found != nil {
+		if rt := found.pick(normalizeVersion(version)); rt != nil {
+			return rt, true
+		}
+	}
+	*params = (*params)[:n]
+	return nil, false
+}
+
+// lookup returns the node path leads to in the tree of method, holding the
+// routes of every version.
+func (r *Router) lookup(method, path string, params *Params) *radixNode {
 	path = dealSlash(path)
+	key := path
+	if r.ignoreCase {
+		key = lowerASCII(path)
+	}
+
+	table := r.current()
+	if table == nil {
+		return nil
+	}
+
+	if tree, ok := table.trees[method]; ok {
+		n := len(*params)
+		if found := tree.lookup(path, key, 0, params); found != nil {
+			return found
+		}
+		*params = (*params)[:n]
+	}
+
+	if method == http.MethodHead {
+		return r.lookup(http.MethodGet, path, params)
+	}
+	return nil
+}
+
+// Allowed returns the methods path is routable with, sorted. HEAD is implied
+// by GET and OPTIONS by any other method, since both are answered
+// automatically. Routes registered for a version only are left out, see
+// AllowedVersion.
+func (r *Router) Allowed(path string) []string {
+	return r.AllowedVersion(path, "")
+}
 
-	// match regular routes first
-	if router, ok := r.routers[method]; ok {
-		if controller, ok := router[path]; ok {
-			return controller, nil, true
+// AllowedVersion is Allowed for a request asking for an API version, a method
+// counts when a route of it is picked for version, see RouteVersion.
+func (r *Router) AllowedVersion(path, version string) []string {
+	table := r.current()
+	if table == nil {
+		return nil
+	}
+
+	version = normalizeVersion(version)
+	var allowed []string
+	var params Params
+	for method := range table.trees {
+		if n := r.lookup(method, path, &params); n != nil && n.pick(version) != nil {
+			allowed = append(allowed, method)
 		}
 	}
+	if len(allowed) == 0 {
+		return nil
+	}
 
-	// re match wild routes
-	if trie, ok := r.wildRouters[method]; ok {
-		return trie.Get(path)
+	if slices.Contains(allowed, http.MethodGet) && !slices.Contains(allowed, http.MethodHead) {
+		allowed = append(allowed, http.MethodHead)
+	}
+	if !slices.Contains(allowed, http.MethodOptions) {
+		allowed = append(allowed, http.MethodOptions)
 	}
+	slices.Sort(allowed)
+	return allowed
+}
+
+// Routes describes every registered route, sorted by pattern and method.
+func (r *Router) Routes() []RouteInfo {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
 
-	// finally match static routes
-	if method == http.MethodGet && strings.HasPrefix(path, "/") {
-		if controller, ok := r.static[path]; ok {
-			return controller, nil, true
+	infos := make([]RouteInfo, 0, len(r.routes))
+	for _, rt := range r.routes {
+		infos = append(infos, rt.info())
+	}
+	slices.SortStableFunc(infos, func(a, b RouteInfo) int {
+		if c := strings.Compare(a.Pattern, b.Pattern); c != 0 {
+			return c
 		}
+		return strings.Compare(a.Method, b.Method)
+	})
+	return infos
+}
+
+func (rt *Route) info() RouteInfo {
+	info := RouteInfo{
+		Host:       rt.router.host,
+		Method:     rt.method,
+		Pattern:    rt.path,
+		Name:       rt.name,
+		Version:    rt.version,
+		Controller: controllerName(rt.controller),
+		Meta:       rt.meta,
+	}
+	for _, mw := range rt.middlewares {
+		info.Middlewares = append(info.Middlewares, funcName(mw))
+	}
+	return info
+}
+
+func funcName(f any) string {
+	fn := runtime.FuncForPC(reflect.ValueOf(f).Pointer())
+	if fn == nil {
+		return "unknown"
+	}
+	return fn.Name()
+}
+
+// Name names the route for URLFor. A name can be shared by routes of the same
+// path, e.g. those registered by OnAny, but not by different paths.
+func (rt *Route) Name(name string) *Route {
+	rt.router.mu.Lock()
+	defer rt.router.mu.Unlock()
+
+	if other, ok := rt.router.names[name]; ok && other.path != rt.path {
+		rt.router.errs = append(rt.router.errs, &RouteError{route: rt, existing: other, reason: "duplicate route name " + name})
+		return rt
+	}
+	rt.name = name
+	rt.router.names[name] = rt
+	return rt
+}
+
+func (rs Routes) Name(name string) Routes {
+	for _, rt := range rs {
+		rt.Name(name)
+	}
+	return rs
+}
+
+func (r *Router) hasName(name string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	_, ok := r.names[name]
+	return ok
+}
+
+// URLFor builds the path of the route named name, filling its wild and
+// catch-all segments from params and appending query when it is not empty.
+func (r *Router) URLFor(name string, params map[string]string, query url.Values) (string, error) {
+	r.mu.RLock()
+	rt, ok := r.names[name]
+	r.mu.RUnlock()
+	if !ok {
+		return "", fmt.Errorf("route %q not found", name)
+	}
+
+	var sb strings.Builder
+	for _, seg := range splitPath(rt.path) {
+		w := seg.word
+		sb.WriteByte('/')
+		switch {
+		case isCatchAllWord(w):
+			value := strings.Trim(params[w[1:]], "/")
+			parts := strings.Split(value, "/")
+			for i, part := range parts {
+				parts[i] = url.PathEscape(part)
+			}
+			sb.WriteString(strings.Join(parts, "/"))
+		case isWildWord(w):
+			key, constraint, err := parseWildWord(w)
+			if err != nil {
+				return "", err
+			}
+			value, ok := params[key]
+			if !ok || value == "" {
+				return "", fmt.Errorf("route %q: missing param %q", name, key)
+			}
+			if constraint != nil && !constraint.match(value) {
+				return "", fmt.Errorf("route %q: param %q does not match <%s>: %s", name, key, constraint, value)
+			}
+			sb.WriteString(url.PathEscape(value))
+		default:
+			sb.WriteString(w)
+		}
+	}
+
+	u := strings.TrimRight(sb.String(), "/")
+	if u == "" {
+		u = "/"
+	}
+	if len(query) > 0 {
+		u += "?" + query.Encode()
 	}
-	return nil, nil, false
+	return u, nil
 }
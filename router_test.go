@@ -0,0 +1,823 @@
+package golitekit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github/hsj/GoLiteKit/env"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+type echoController struct {
+	BaseController
+
+	Body string
+}
+
+func (c *echoController) Serve(ctx context.Context) error {
+	c.ServeRawData(c.Body)
+	return nil
+}
+
+func newTestServer() *Server {
+	return &Server{
+		router:           NewRouter(),
+		mq:               NewMiddlewareQueue(ContextAsMiddleware()),
+		notFound:         &NotFoundController{},
+		methodNotAllowed: &MethodNotAllowedController{},
+	}
+}
+
+const (
+	notFoundBody         = `{"status":404,"msg":"Not Found"}`
+	methodNotAllowedBody = `{"status":405,"msg":"Method Not Allowed"}`
+)
+
+func traceMiddleware(trace *[]string, name string) Middleware {
+	return func(ctx context.Context, queue MiddlewareQueue) error {
+		*trace = append(*trace, name)
+		return queue.Next(ctx)
+	}
+}
+
+func serve(s *Server, method, target string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(method, target, nil))
+	return w
+}
+
+func TestGroup(t *testing.T) {
+	var trace []string
+	s := newTestServer()
+	s.OnGet("/ping", &echoController{Body: "pong"})
+
+	api := s.Group("/api", traceMiddleware(&trace, "api"))
+	api.OnGet("/users", &echoController{Body: "users"})
+
+	v1 := api.Group("/v1/", traceMiddleware(&trace, "v1"))
+	v1.OnGet("/user/:id", &echoController{Body: "user"})
+	v1.OnPost("/", &echoController{Body: "v1"})
+
+	cases := []struct {
+		method string
+		path   string
+		status int
+		body   string
+		trace  string
+	}{
+		{http.MethodGet, "/ping", http.StatusOK, "pong", ""},
+		{http.MethodGet, "/api/users", http.StatusOK, "users", "api"},
+		{http.MethodGet, "/api/v1/user/1", http.StatusOK, "user", "api,v1"},
+		{http.MethodPost, "/api/v1", http.StatusOK, "v1", "api,v1"},
+		{http.MethodGet, "/users", http.StatusNotFound, notFoundBody, ""},
+		{http.MethodGet, "/v1/user/1", http.StatusNotFound, notFoundBody, ""},
+	}
+
+	for _, c := range cases {
+		trace = trace[:0]
+		w := serve(s, c.method, c.path)
+		if w.Code != c.status {
+			t.Errorf("%s %s: expected status %d, got %d", c.method, c.path, c.status, w.Code)
+		}
+		if w.Body.String() != c.body {
+			t.Errorf("%s %s: expected body %q, got %q", c.method, c.path, c.body, w.Body.String())
+		}
+		if got := strings.Join(trace, ","); got != c.trace {
+			t.Errorf("%s %s: expected middlewares %q, got %q", c.method, c.path, c.trace, got)
+		}
+	}
+}
+
+func TestStatic(t *testing.T) {
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, "css"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "css", "main.css"), []byte("body{}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "index.html"), []byte("<html></html>"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	s := newTestServer()
+	s.Static("/static", root)
+
+	cases := []struct {
+		path   string
+		status int
+		body   string
+	}{
+		{"/static/css/main.css", http.StatusOK, "body{}"},
+		{"/static/index.html", http.StatusOK, "<html></html>"},
+		{"/static/../index.html", http.StatusNotFound, notFoundBody},
+		{"/static/nope.css", http.StatusNotFound, notFoundBody},
+		{"/index.html", http.StatusNotFound, notFoundBody},
+	}
+	for _, c := range cases {
+		w := serve(s, http.MethodGet, c.path)
+		if w.Code != c.status {
+			t.Errorf("GET %s: expected status %d, got %d", c.path, c.status, w.Code)
+		}
+		if w.Body.String() != c.body {
+			t.Errorf("GET %s: expected body %q, got %q", c.path, c.body, w.Body.String())
+		}
+	}
+
+	w := serve(s, http.MethodGet, "/static")
+	if !strings.Contains(w.Body.String(), `<a href="index.html">index.html</a>`) {
+		t.Errorf("GET /static: expected directory listing, got %q", w.Body.String())
+	}
+}
+
+func TestValidate(t *testing.T) {
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "app.js"), []byte("app"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	s := newTestServer()
+	s.Static("/static", root)
+	s.OnGet("/static/app.js", &echoController{Body: "shadow"})
+	s.OnGet("/static/other.js", &echoController{Body: "other"})
+	s.OnGet("/user/:id", &echoController{})
+	s.OnGet("/user/:name", &echoController{})
+	s.Host("api.example.com").OnGet("/a", &echoController{})
+	s.Host("api.example.com").OnGet("/a", &echoController{})
+	err := s.Validate()
+	if err == nil {
+		t.Fatal("Expected route errors")
+	}
+	msgs := strings.Split(err.Error(), "\n")
+	if len(msgs) != 3 {
+		t.Fatalf("Expected 3 errors, got %q", msgs)
+	}
+	for _, want := range []string{"shadows a file served by Static", "param :name differs from :id", "duplicate route"} {
+		if !slices.ContainsFunc(msgs, func(msg string) bool {
+			return strings.Contains(msg, want) && strings.Count(msg, "router_test.go:") == 2
+		}) {
+			t.Errorf("Expected an error %q naming both sites, got %q", want, msgs)
+		}
+	}
+
+	ok := newTestServer()
+	ok.OnGet("/user/:id", &echoController{})
+	ok.OnPost("/user/:id", &echoController{})
+	if err := ok.Validate(); err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+}
+
+func TestRouteMeta(t *testing.T) {
+	var seen []string
+	s := newTestServer()
+	s.mq.Use(func(ctx context.Context, queue MiddlewareQueue) error {
+		gcx := GetContext(ctx)
+		seen = append(seen, fmt.Sprintf("%s %v", gcx.RoutePattern(), gcx.RouteMeta().Get("scope")))
+		return queue.Next(ctx)
+	})
+
+	s.OnGet("/user/:id", &echoController{Body: "user"}, Meta{"scope": "user"})
+	admin := s.Group("/admin")
+	admin.OnDelete("/user/:id", &echoController{Body: "deleted"}, Meta{"scope": "admin"}, Meta{"audit": "user"})
+	s.OnGet("/ping", &echoController{Body: "pong"})
+
+	serve(s, http.MethodGet, "/user/1")
+	serve(s, http.MethodDelete, "/admin/user/1")
+	serve(s, http.MethodGet, "/ping")
+	serve(s, http.MethodGet, "/missing")
+	want := []string{"/user/:id user", "/admin/user/:id admin", "/ping <nil>", " <nil>"}
+	if !slices.Equal(seen, want) {
+		t.Errorf("Expected %q, got %q", want, seen)
+	}
+
+	for _, info := range s.Routes() {
+		if info.Pattern == "/admin/user/:id" && (info.Meta.String("scope") != "admin" || info.Meta.String("audit") != "user") {
+			t.Errorf("Expected merged meta, got %v", info.Meta)
+		}
+	}
+}
+
+func TestRouteOptions(t *testing.T) {
+	var trace []string
+	s := newTestServer()
+	s.mq.Use(func(ctx context.Context, queue MiddlewareQueue) error {
+		gcx := GetContext(ctx)
+		trace = append(trace, fmt.Sprintf("%v@%s", gcx.RouteMeta().Get("scope"), gcx.RouteVersion()))
+		return queue.Next(ctx)
+	})
+
+	// the exported Router takes the same options as Server and Group
+	s.router.OnGet("/r", &echoController{Body: "r"}, Meta{"scope": "router"}, Version("v1"), Use(traceMiddleware(&trace, "route")))
+	s.Group("/g", traceMiddleware(&trace, "group")).OnGet("/x", &echoController{Body: "x"}, Use(traceMiddleware(&trace, "route")))
+
+	cases := []struct {
+		target string
+		body   string
+		trace  string
+	}{
+		{"/r", "r", "router@1,route"},
+		{"/g/x", "x", "<nil>@,group,route"},
+	}
+	for _, c := range cases {
+		trace = trace[:0]
+		req := httptest.NewRequest(http.MethodGet, c.target, nil)
+		req.Header.Set("Accept-Version", "1")
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, req)
+		if got := strings.Join(trace, ","); w.Body.String() != c.body || got != c.trace {
+			t.Errorf("GET %s: expected %q %q, got %q %q", c.target, c.body, c.trace, w.Body.String(), got)
+		}
+	}
+
+	// Use on Replace adds to the kept middlewares of the replaced route only
+	old, _, _ := s.router.Route(http.MethodGet, "/g/x")
+	s.ReplaceRoute(http.MethodGet, "/g/x", &echoController{Body: "y"}, Use(traceMiddleware(&trace, "extra")))
+	trace = trace[:0]
+	if w := serve(s, http.MethodGet, "/g/x"); w.Body.String() != "y" || strings.Join(trace, ",") != "<nil>@,group,route,extra" {
+		t.Errorf("Expected the added middleware to run, got %q %q", w.Body.String(), trace)
+	}
+	if len(old.middlewares) != 2 {
+		t.Errorf("Expected the replaced route to keep its middlewares, got %d", len(old.middlewares))
+	}
+}
+
+func TestVersion(t *testing.T) {
+	s := newTestServer()
+	s.OnGet("/items/:id", &echoController{Body: "v0"})
+	s.OnGet("/items/:id", &echoController{Body: "v1"}, Version("v1"))
+	s.OnGet("/items/:id", &echoController{Body: "v2"}, Version("2"))
+	s.OnGet("/beta", &echoController{Body: "beta"}, Version("3"))
+
+	cases := []struct {
+		target string
+		header string
+		value  string
+		status int
+		body   string
+	}{
+		{"/items/1", "", "", http.StatusOK, "v0"},
+		{"/items/1", "Accept-Version", "1", http.StatusOK, "v1"},
+		{"/items/1", "Accept-Version", "v2", http.StatusOK, "v2"},
+		{"/items/1", "Accept", "text/html, application/vnd.app+json; version=2", http.StatusOK, "v2"},
+		{"/items/1?version=1", "", "", http.StatusOK, "v1"},
+		{"/items/1?version=1", "Accept-Version", "2", http.StatusOK, "v2"},
+		{"/items/1", "Accept-Version", "9", http.StatusOK, "v0"},
+		{"/beta", "Accept-Version", "3", http.StatusOK, "beta"},
+		{"/beta", "", "", http.StatusNotFound, notFoundBody},
+	}
+	for _, c := range cases {
+		req := httptest.NewRequest(http.MethodGet, c.target, nil)
+		if c.header != "" {
+			req.Header.Set(c.header, c.value)
+		}
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, req)
+		if w.Code != c.status || w.Body.String() != c.body {
+			t.Errorf("GET %s %s=%q: expected %d %q, got %d %q", c.target, c.header, c.value, c.status, c.body, w.Code, w.Body.String())
+		}
+		if vary := w.Header().Values("Vary"); !slices.Equal(vary, []string{"Accept-Version", "Accept"}) {
+			t.Errorf("GET %s: expected Vary header", c.target)
+		}
+	}
+
+	// without a version asked nor a default, /beta is not routable at all
+	methods := []struct {
+		method  string
+		version string
+		status  int
+		allow   string
+	}{
+		{http.MethodPost, "", http.StatusNotFound, ""},
+		{http.MethodOptions, "", http.StatusNotFound, ""},
+		{http.MethodPost, "3", http.StatusMethodNotAllowed, "GET, HEAD, OPTIONS"},
+		{http.MethodOptions, "3", http.StatusNoContent, "GET, HEAD, OPTIONS"},
+	}
+	for _, c := range methods {
+		req := httptest.NewRequest(c.method, "/beta", nil)
+		req.Header.Set("Accept-Version", c.version)
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, req)
+		if w.Code != c.status || w.Header().Get("Allow") != c.allow {
+			t.Errorf("%s /beta version %q: expected %d Allow %q, got %d %q", c.method, c.version, c.status, c.allow, w.Code, w.Header().Get("Allow"))
+		}
+	}
+
+	if allowed := s.router.AllowedVersion("/beta", "v3"); !slices.Equal(allowed, []string{"GET", "HEAD", "OPTIONS"}) {
+		t.Errorf("Expected the v3 route to be allowed, got %v", allowed)
+	}
+
+	s.defaultVersion = "2"
+	if w := serve(s, http.MethodGet, "/items/1"); w.Body.String() != "v2" {
+		t.Errorf("Expected the default version to be served, got %q", w.Body.String())
+	}
+
+	s.ReplaceRoute(http.MethodGet, "/items/:id", &echoController{Body: "v1 replaced"}, Version("1"))
+	if rt, _, ok := s.router.RouteVersion(http.MethodGet, "/items/1", "1"); !ok || rt.controller.(*echoController).Body != "v1 replaced" {
+		t.Errorf("Expected version 1 to be replaced")
+	}
+	if errs := s.router.Validate(); len(errs) != 0 {
+		t.Errorf("Expected no errors, got %v", errs)
+	}
+	s.OnGet("/items/:id", &echoController{}, Version("2"))
+	if errs := s.router.Validate(); len(errs) != 1 {
+		t.Errorf("Expected a duplicate version error, got %v", errs)
+	}
+}
+
+func TestMethods(t *testing.T) {
+	s := newTestServer()
+	s.OnGet("/user/:id", &echoController{Body: "get"})
+	s.OnPatch("/user/:id", &echoController{Body: "patch"})
+	s.Handle("delete", "/user/:id", &echoController{Body: "delete"})
+	s.OnHead("/head", &echoController{Body: "head"})
+	s.OnOptions("/options", &echoController{Body: "options"})
+	s.OnAny("/any", &echoController{Body: "any"})
+
+	cases := []struct {
+		method string
+		path   string
+		status int
+		body   string
+		allow  string
+	}{
+		{http.MethodGet, "/user/1", http.StatusOK, "get", ""},
+		{http.MethodHead, "/user/1", http.StatusOK, "get", ""},
+		{http.MethodPatch, "/user/1", http.StatusOK, "patch", ""},
+		{http.MethodDelete, "/user/1", http.StatusOK, "delete", ""},
+		{http.MethodOptions, "/user/1", http.StatusNoContent, "", "DELETE, GET, HEAD, OPTIONS, PATCH"},
+		{http.MethodPost, "/user/1", http.StatusMethodNotAllowed, methodNotAllowedBody, "DELETE, GET, HEAD, OPTIONS, PATCH"},
+		{http.MethodHead, "/head", http.StatusOK, "head", ""},
+		{http.MethodGet, "/head", http.StatusMethodNotAllowed, methodNotAllowedBody, "HEAD, OPTIONS"},
+		{http.MethodOptions, "/options", http.StatusOK, "options", ""},
+		{http.MethodPut, "/any", http.StatusOK, "any", ""},
+		{http.MethodTrace, "/any", http.StatusOK, "any", ""},
+		{http.MethodGet, "/none", http.StatusNotFound, notFoundBody, ""},
+		{http.MethodOptions, "/none", http.StatusNotFound, notFoundBody, ""},
+	}
+
+	for _, c := range cases {
+		w := serve(s, c.method, c.path)
+		if w.Code != c.status {
+			t.Errorf("%s %s: expected status %d, got %d", c.method, c.path, c.status, w.Code)
+		}
+		if w.Body.String() != c.body {
+			t.Errorf("%s %s: expected body %q, got %q", c.method, c.path, c.body, w.Body.String())
+		}
+		if got := w.Header().Get("Allow"); got != c.allow {
+			t.Errorf("%s %s: expected Allow %q, got %q", c.method, c.path, c.allow, got)
+		}
+	}
+}
+
+func TestURLFor(t *testing.T) {
+	s := newTestServer()
+	s.OnGet("/", &echoController{}).Name("home")
+	s.OnGet("/user/:id<int>", &echoController{}).Name("user.show")
+	s.Group("/api").OnAny("/post/:slug/comments", &echoController{}).Name("post.comments")
+	s.OnGet("/files/*filepath", &echoController{}).Name("files")
+
+	cases := []struct {
+		name   string
+		params map[string]string
+		query  url.Values
+		url    string
+		err    bool
+	}{
+		{"home", nil, nil, "/", false},
+		{"home", nil, url.Values{"q": {"a b"}}, "/?q=a+b", false},
+		{"user.show", map[string]string{"id": "42"}, nil, "/user/42", false},
+		{"user.show", map[string]string{"id": "abc"}, nil, "", true},
+		{"user.show", nil, nil, "", true},
+		{"post.comments", map[string]string{"slug": "a b/c"}, url.Values{"page": {"2"}}, "/api/post/a%20b%2Fc/comments?page=2", false},
+		{"files", map[string]string{"filepath": "css/main file.css"}, nil, "/files/css/main%20file.css", false},
+		{"files", nil, nil, "/files", false},
+		{"missing", nil, nil, "", true},
+	}
+
+	for _, c := range cases {
+		u, err := s.URLFor(c.name, c.params, c.query)
+		if c.err {
+			if err == nil {
+				t.Errorf("URLFor(%s, %v): expected error, got %s", c.name, c.params, u)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("URLFor(%s, %v): unexpected error %v", c.name, c.params, err)
+			continue
+		}
+		if u != c.url {
+			t.Errorf("URLFor(%s, %v): expected %s, got %s", c.name, c.params, c.url, u)
+		}
+	}
+
+	s.OnGet("/other", &echoController{}).Name("home")
+	if err := s.Validate(); err == nil || !strings.Contains(err.Error(), "duplicate route name home") {
+		t.Errorf("Expected duplicate route name error, got %v", err)
+	}
+}
+
+func TestRoutes(t *testing.T) {
+	var trace []string
+	s := newTestServer()
+	s.OnPost("/user", &echoController{})
+	s.Group("/admin", traceMiddleware(&trace, "admin")).OnGet("/stats", &echoController{}).Name("admin.stats")
+	s.Static("/static", t.TempDir())
+	s.ExposeRoutes("/debug/routes")
+
+	expected := []RouteInfo{
+		{Method: http.MethodGet, Pattern: "/admin/stats", Name: "admin.stats", Controller: "*golitekit.echoController"},
+		{Method: http.MethodGet, Pattern: "/debug/routes", Controller: "*golitekit.RoutesController"},
+		{Method: http.MethodGet, Pattern: "/static/*filepath", Controller: "*golitekit.StaticController"},
+		{Method: http.MethodPost, Pattern: "/user", Controller: "*golitekit.echoController"},
+	}
+
+	routes := s.Routes()
+	if len(routes) != len(expected) {
+		t.Fatalf("expected %d routes, got %v", len(expected), routes)
+	}
+	for i, info := range routes {
+		e := expected[i]
+		if info.Method != e.Method || info.Pattern != e.Pattern || info.Name != e.Name || info.Controller != e.Controller {
+			t.Errorf("route %d: expected %+v, got %+v", i, e, info)
+		}
+	}
+	if mws := routes[0].Middlewares; len(mws) != 1 || !strings.Contains(mws[0], "traceMiddleware") {
+		t.Errorf("expected traceMiddleware attached to /admin/stats, got %v", mws)
+	}
+
+	w := serve(s, http.MethodGet, "/debug/routes")
+	var res struct {
+		Data []RouteInfo `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil {
+		t.Fatalf("unexpected body %q: %v", w.Body.String(), err)
+	}
+	if len(res.Data) != len(expected) {
+		t.Errorf("expected %d routes served, got %v", len(expected), res.Data)
+	}
+}
+
+type errorController struct {
+	RestController
+}
+
+func (c *errorController) Serve(ctx context.Context) error {
+	c.ServeError(GetContext(ctx).status, "custom")
+	return nil
+}
+
+func TestNotFound(t *testing.T) {
+	var trace []string
+	s := newTestServer()
+	s.mq.Use(traceMiddleware(&trace, "global"))
+	s.OnGet("/user", &echoController{})
+
+	w := serve(s, http.MethodGet, "/none")
+	if w.Code != http.StatusNotFound || w.Body.String() != notFoundBody {
+		t.Errorf("expected default not found response, got %d %q", w.Code, w.Body.String())
+	}
+	if w.Header().Get("Content-Type") != "application/json" {
+		t.Errorf("expected json content type, got %q", w.Header().Get("Content-Type"))
+	}
+	if len(trace) != 1 {
+		t.Errorf("expected not found request to run through middlewares, got %v", trace)
+	}
+
+	s.NotFound(&errorController{})
+	s.MethodNotAllowed(&errorController{})
+
+	w = serve(s, http.MethodGet, "/none")
+	if w.Code != http.StatusNotFound || w.Body.String() != `{"status":404,"msg":"custom"}` {
+		t.Errorf("expected custom not found response, got %d %q", w.Code, w.Body.String())
+	}
+
+	w = serve(s, http.MethodPost, "/user")
+	if w.Code != http.StatusMethodNotAllowed || w.Body.String() != `{"status":405,"msg":"custom"}` {
+		t.Errorf("expected custom method not allowed response, got %d %q", w.Code, w.Body.String())
+	}
+	if w.Header().Get("Allow") != "GET, HEAD, OPTIONS" {
+		t.Errorf("expected Allow header, got %q", w.Header().Get("Allow"))
+	}
+	if len(trace) != 3 {
+		t.Errorf("expected every request to run through middlewares, got %v", trace)
+	}
+}
+
+func TestPathPolicy(t *testing.T) {
+	cases := []struct {
+		policy   string
+		path     string
+		status   int
+		body     string
+		location string
+	}{
+		{env.PathLenient, "/a/b", http.StatusOK, "ab", ""},
+		{env.PathLenient, "/a/b/", http.StatusOK, "ab", ""},
+		{env.PathLenient, "/a//b", http.StatusOK, "ab", ""},
+		{env.PathLenient, "//user//123", http.StatusOK, "user", ""},
+		{env.PathLenient, "/a/./c/../b", http.StatusOK, "ab", ""},
+		{env.PathLenient, "/user/1/../2", http.StatusOK, "user", ""},
+		{env.PathStrict, "/a/b", http.StatusOK, "ab", ""},
+		{env.PathStrict, "/a/b/", http.StatusNotFound, notFoundBody, ""},
+		{env.PathStrict, "/a//b", http.StatusNotFound, notFoundBody, ""},
+		{env.PathStrict, "/a/./b", http.StatusNotFound, notFoundBody, ""},
+		{env.PathRedirect, "/a/b", http.StatusOK, "ab", ""},
+		{env.PathRedirect, "/a/b/?x=1", http.StatusMovedPermanently, "", "/a/b?x=1"},
+		{env.PathRedirect, "/a//c/../b", http.StatusMovedPermanently, "", "/a/b"},
+	}
+
+	for _, c := range cases {
+		s := newTestServer()
+		s.pathPolicy = c.policy
+		s.redirectCode = http.StatusMovedPermanently
+		s.OnGet("/a/b", &echoController{Body: "ab"})
+		s.OnGet("/user/:id", &echoController{Body: "user"})
+
+		w := serve(s, http.MethodGet, c.path)
+		if w.Code != c.status || w.Body.String() != c.body {
+			t.Errorf("%s %s: expected %d %q, got %d %q", c.policy, c.path, c.status, c.body, w.Code, w.Body.String())
+		}
+		if got := w.Header().Get("Location"); got != c.location {
+			t.Errorf("%s %s: expected Location %q, got %q", c.policy, c.path, c.location, got)
+		}
+	}
+}
+
+func TestIgnoreCase(t *testing.T) {
+	s := newTestServer()
+	s.router.IgnoreCase(true)
+	s.OnGet("/About/Team", &echoController{Body: "team"})
+	s.OnGet("/User/:Name<[A-Z]+>", &echoController{Body: "user"})
+
+	cases := []struct {
+		path   string
+		status int
+		body   string
+	}{
+		{"/about/team", http.StatusOK, "team"},
+		{"/ABOUT/TEAM", http.StatusOK, "team"},
+		{"/user/BOB", http.StatusOK, "user"},
+		{"/user/bob", http.StatusNotFound, notFoundBody},
+	}
+	for _, c := range cases {
+		w := serve(s, http.MethodGet, c.path)
+		if w.Code != c.status || w.Body.String() != c.body {
+			t.Errorf("%s: expected %d %q, got %d %q", c.path, c.status, c.body, w.Code, w.Body.String())
+		}
+	}
+
+	_, params, _ := s.router.Route(http.MethodGet, "/USER/ALICE")
+	if params.Get("Name") != "ALICE" {
+		t.Errorf("expected params to keep their case, got %v", params)
+	}
+}
+
+type paramController struct {
+	BaseController
+
+	Key string
+}
+
+func (c *paramController) Serve(ctx context.Context) error {
+	c.ServeRawData(c.RouterParamString(c.Key, ""))
+	return nil
+}
+
+func TestHost(t *testing.T) {
+	s := newTestServer()
+	s.OnGet("/", &echoController{Body: "default"})
+	s.Host("api.example.com").OnGet("/", &echoController{Body: "api"})
+	s.Host("*.example.com").OnGet("/", &paramController{Key: HostParam})
+	s.Host("*.eu.example.com").Group("/v1").OnGet("/user/:id", &paramController{Key: "id"})
+	s.Host("*.example.com").OnGet("/tenant/:id", &paramController{Key: HostParam}).Name("tenant")
+
+	cases := []struct {
+		host   string
+		path   string
+		status int
+		body   string
+	}{
+		{"example.org", "/", http.StatusOK, "default"},
+		{"example.com", "/", http.StatusOK, "default"},
+		{"api.example.com", "/", http.StatusOK, "api"},
+		{"API.Example.com:8080", "/", http.StatusOK, "api"},
+		{"acme.example.com", "/", http.StatusOK, "acme"},
+		{"a.b.example.com", "/", http.StatusOK, "a.b"},
+		{"acme.example.com", "/tenant/1", http.StatusOK, "acme"},
+		{"acme.eu.example.com", "/v1/user/7", http.StatusOK, "7"},
+		{"acme.eu.example.com", "/", http.StatusNotFound, notFoundBody},
+		{"api.example.com", "/tenant/1", http.StatusNotFound, notFoundBody},
+	}
+
+	for _, c := range cases {
+		req := httptest.NewRequest(http.MethodGet, c.path, nil)
+		req.Host = c.host
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, req)
+		if w.Code != c.status || w.Body.String() != c.body {
+			t.Errorf("%s%s: expected %d %q, got %d %q", c.host, c.path, c.status, c.body, w.Code, w.Body.String())
+		}
+	}
+
+	if u, err := s.URLFor("tenant", map[string]string{"id": "1"}, nil); err != nil || u != "/tenant/1" {
+		t.Errorf("expected URLFor to find host routes, got %q %v", u, err)
+	}
+	if routes := s.Routes(); len(routes) != 5 || routes[1].Host != "api.example.com" {
+		t.Errorf("expected host routes listed after default ones, got %+v", routes)
+	}
+}
+
+func TestMount(t *testing.T) {
+	var trace []string
+	s := newTestServer()
+	s.mq.Use(traceMiddleware(&trace, "global"))
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/vars", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("vars"))
+	})
+	mux.HandleFunc("/debug/", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("debug " + GetContext(r.Context()).RouterParams().Get(MountPathParam)))
+	})
+	s.Mount("/debug", mux)
+
+	s.HandleFunc(http.MethodPost, "/hook/:id", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+		w.Write([]byte("hook " + GetContext(r.Context()).RouterParams().Get("id")))
+	})
+
+	sub := newTestServer()
+	sub.OnGet("/", &echoController{Body: "sub"})
+	sub.Group("/admin", traceMiddleware(&trace, "admin")).OnGet("/user/:id", &paramController{Key: "id"}).Name("sub.user")
+	s.Group("/api", traceMiddleware(&trace, "api")).Mount("/sub", sub)
+
+	cases := []struct {
+		method string
+		path   string
+		status int
+		body   string
+		trace  string
+	}{
+		{http.MethodGet, "/debug/vars", http.StatusOK, "vars", "global"},
+		{http.MethodPut, "/debug/pprof/heap", http.StatusOK, "debug pprof/heap", "global"},
+		{http.MethodPost, "/hook/3", http.StatusAccepted, "hook 3", "global"},
+		{http.MethodGet, "/hook/3", http.StatusMethodNotAllowed, methodNotAllowedBody, "global"},
+		{http.MethodGet, "/api/sub", http.StatusOK, "sub", "global,api"},
+		{http.MethodGet, "/api/sub/admin/user/5", http.StatusOK, "5", "global,api,admin"},
+	}
+
+	for _, c := range cases {
+		trace = trace[:0]
+		w := serve(s, c.method, c.path)
+		if w.Code != c.status || w.Body.String() != c.body {
+			t.Errorf("%s %s: expected %d %q, got %d %q", c.method, c.path, c.status, c.body, w.Code, w.Body.String())
+		}
+		if got := strings.Join(trace, ","); got != c.trace {
+			t.Errorf("%s %s: expected middlewares %q, got %q", c.method, c.path, c.trace, got)
+		}
+	}
+
+	if u, err := s.URLFor("sub.user", map[string]string{"id": "5"}, nil); err != nil || u != "/api/sub/admin/user/5" {
+		t.Errorf("expected mounted route names to be kept, got %q %v", u, err)
+	}
+
+	// mounted handlers read the body themselves, beyond the default limit
+	s.Mount("/proxy", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusRequestEntityTooLarge)
+			return
+		}
+		fmt.Fprintf(w, "%d %.7s", len(body), body)
+	}))
+	bodies := map[string]string{
+		MIMEForm:                   "a=1&b=2",
+		"application/octet-stream": "a=1&b=2" + strings.Repeat("x", 11<<20),
+	}
+	for contentType, body := range bodies {
+		req := httptest.NewRequest(http.MethodPost, "/proxy", strings.NewReader(body))
+		req.Header.Set("Content-Type", contentType)
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, req)
+		if want := fmt.Sprintf("%d a=1&b=2", len(body)); w.Code != http.StatusOK || w.Body.String() != want {
+			t.Errorf("POST /proxy %s: expected %q, got %d %q", contentType, want, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestMountTimeout(t *testing.T) {
+	s := newTestServer()
+	s.mq.Use(func(ctx context.Context, queue MiddlewareQueue) error {
+		return serveWithTimeout(ctx, queue, 20*time.Millisecond)
+	})
+
+	done := make(chan error, 1)
+	s.HandleFunc(http.MethodGet, "/slow", func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+		time.Sleep(20 * time.Millisecond)
+		w.Header().Set("X-Late", "1")
+		w.WriteHeader(http.StatusAccepted)
+		_, err := w.Write([]byte("late"))
+		done <- err
+	})
+
+	w := serve(s, http.MethodGet, "/slow")
+	if err := <-done; err != http.ErrHandlerTimeout {
+		t.Errorf("expected late writes to fail with ErrHandlerTimeout, got %v", err)
+	}
+	if w.Code != http.StatusServiceUnavailable || w.Body.String() != `{"status":503,"msg":"Service Unavailable"}` || w.Header().Get("X-Late") != "" {
+		t.Errorf("expected 503 without the late writes, got %d %q %v", w.Code, w.Body.String(), w.Header())
+	}
+}
+
+func TestReplaceRoute(t *testing.T) {
+	var trace []string
+	s := newTestServer()
+	s.Group("/api", traceMiddleware(&trace, "api")).OnGet("/user/:id", &echoController{Body: "v1"}).Name("user")
+
+	s.ReplaceRoute(http.MethodGet, "/api/user/:id", &echoController{Body: "v2"})
+	if w := serve(s, http.MethodGet, "/api/user/1"); w.Body.String() != "v2" {
+		t.Errorf("expected replaced controller, got %q", w.Body.String())
+	}
+	if len(trace) != 1 {
+		t.Errorf("expected replaced route to keep its middlewares, got %v", trace)
+	}
+	if u, err := s.URLFor("user", map[string]string{"id": "1"}, nil); err != nil || u != "/api/user/1" {
+		t.Errorf("expected replaced route to keep its name, got %q %v", u, err)
+	}
+
+	s.ReplaceRoute(http.MethodPost, "/api/user", &echoController{Body: "created"})
+	if w := serve(s, http.MethodPost, "/api/user"); w.Body.String() != "created" {
+		t.Errorf("expected added route, got %q", w.Body.String())
+	}
+
+	if !s.RemoveRoute(http.MethodGet, "/api/user/:id") {
+		t.Errorf("expected route to be removed")
+	}
+	if s.RemoveRoute(http.MethodGet, "/api/user/:id") {
+		t.Errorf("expected route to be removed only once")
+	}
+	if w := serve(s, http.MethodGet, "/api/user/1"); w.Code != http.StatusNotFound {
+		t.Errorf("expected removed route to be not found, got %d", w.Code)
+	}
+	if _, err := s.URLFor("user", nil, nil); err == nil {
+		t.Errorf("expected removed route name to be dropped")
+	}
+	if len(s.Routes()) != 1 {
+		t.Errorf("expected one route left, got %v", s.Routes())
+	}
+}
+
+func TestReplaceRouteWhileServing(t *testing.T) {
+	s := newTestServer()
+	s.OnGet("/a", &echoController{Body: "v0"})
+
+	done := make(chan struct{})
+	errs := make(chan string, 8)
+	for i := 0; i < 4; i++ {
+		go func() {
+			for {
+				select {
+				case <-done:
+					errs <- ""
+					return
+				default:
+				}
+				w := serve(s, http.MethodGet, "/a")
+				if w.Code != http.StatusOK || !strings.HasPrefix(w.Body.String(), "v") {
+					errs <- fmt.Sprintf("unexpected response %d %q", w.Code, w.Body.String())
+					return
+				}
+				serve(s, http.MethodGet, "/b/1")
+			}
+		}()
+	}
+
+	for i := 1; i <= 100; i++ {
+		s.ReplaceRoute(http.MethodGet, "/a", &echoController{Body: fmt.Sprintf("v%d", i)})
+		s.OnGet(fmt.Sprintf("/b/%d", i), &echoController{Body: "b"})
+		s.RemoveRoute(http.MethodGet, fmt.Sprintf("/b/%d", i-1))
+		s.Routes()
+	}
+	close(done)
+
+	for i := 0; i < 4; i++ {
+		if err := <-errs; err != "" {
+			t.Error(err)
+		}
+	}
+	if w := serve(s, http.MethodGet, "/a"); w.Body.String() != "v100" {
+		t.Errorf("expected last replacement to win, got %q", w.Body.String())
+	}
+}
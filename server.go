@@ -2,23 +2,35 @@ package golitekit
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"github/hsj/GoLiteKit/env"
 	"github/hsj/GoLiteKit/logger"
 	"net/http"
+	"net/url"
 	"os"
 	"os/signal"
 	"path/filepath"
 	"strings"
 	"syscall"
+	"text/tabwriter"
 )
 
 type Server struct {
 	addr        string
 	router      Router
+	hosts       hostRouters
 	rateLimiter *RateLimiter
 	mq          MiddlewareQueue
 
+	notFound         Controller
+	methodNotAllowed Controller
+	errorHandler     ErrorHandler
+
+	pathPolicy     string
+	redirectCode   int
+	defaultVersion string
+
 	httpServer http.Server
 	closeChan  chan struct{}
 
@@ -27,8 +39,6 @@ type Server struct {
 }
 
 func New(conf string) *Server {
-	router := NewRouter()
-
 	if err := env.Init(conf); err != nil {
 		fmt.Fprintf(os.Stderr, "env init error: %v", err)
 		return nil
@@ -52,18 +62,33 @@ func New(conf string) *Server {
 
 	mq := NewMiddlewareQueue(LoggerAsMiddleware(logInst, panicLogger), TrackerMiddleware, ContextAsMiddleware(), TimeoutMiddleware)
 
-	return &Server{
-		addr:        env.Addr(),
-		router:      router,
-		rateLimiter: rateLimiter,
-		closeChan:   make(chan struct{}),
-		mq:          mq,
-		logger:      logInst,
-		panicLogger: panicLogger,
+	s := &Server{
+		addr:             env.Addr(),
+		router:           NewRouter(),
+		rateLimiter:      rateLimiter,
+		closeChan:        make(chan struct{}),
+		mq:               mq,
+		notFound:         &NotFoundController{},
+		methodNotAllowed: &MethodNotAllowedController{},
+		pathPolicy:       env.PathPolicy(),
+		redirectCode:     env.RedirectCode(),
+		defaultVersion:   env.DefaultVersion(),
+		logger:           logInst,
+		panicLogger:      panicLogger,
 	}
+	s.router.IgnoreCase(env.CaseInsensitive())
+
+	return s
 }
 
-func (s *Server) Start() {
+// Start validates the routes and serves until the server is shut down by
+// SIGINT or SIGTERM. It returns the route errors, see Validate, without
+// listening, or the error the server failed to listen with.
+func (s *Server) Start() error {
+	if err := s.Validate(); err != nil {
+		return err
+	}
+
 	s.httpServer = http.Server{
 		Addr:           s.addr,
 		ReadTimeout:    env.ReadTimeout(),
@@ -77,19 +102,35 @@ func (s *Server) Start() {
 		s.httpServer.ReadHeaderTimeout = env.ReadHeaderTimeout()
 	}
 
+	if env.RunMode() == "debug" {
+		s.printRoutes()
+	}
+
 	go s.handleSignal()
 
 	var err error
 	if env.TLSCertFile() != "" && env.TLSKeyFile() != "" {
-		s.httpServer.ListenAndServeTLS(env.TLSCertFile(), env.TLSKeyFile())
+		err = s.httpServer.ListenAndServeTLS(env.TLSCertFile(), env.TLSKeyFile())
 	} else {
 		err = s.httpServer.ListenAndServe()
 	}
 
 	if err != nil && err != http.ErrServerClosed {
-		fmt.Printf("server start error: %v", err)
+		return fmt.Errorf("server start error: %w", err)
 	}
 	<-s.closeChan
+	return nil
+}
+
+// Validate reports every route that could not be registered, because its
+// pattern is invalid or conflicts with another route, and every route
+// shadowing a file served by Static, naming the registration sites of both.
+func (s *Server) Validate() error {
+	errs := s.router.Validate()
+	for _, router := range s.hosts.routers() {
+		errs = append(errs, router.Validate()...)
+	}
+	return errors.Join(errs...)
 }
 
 func (s *Server) handleSignal() {
@@ -109,23 +150,92 @@ func (s *Server) handleSignal() {
 	s.closeChan <- struct{}{}
 }
 
-func (s *Server) OnGet(path string, controller Controller) {
-	s.router.OnGet(path, controller)
+func (s *Server) Handle(method, path string, controller Controller, opts ...RouteOption) *Route {
+	return s.router.handle(method, path, controller, nil, opts)
+}
+
+func (s *Server) OnGet(path string, controller Controller, opts ...RouteOption) *Route {
+	return s.Handle(http.MethodGet, path, controller, opts...)
+}
+
+func (s *Server) OnHead(path string, controller Controller, opts ...RouteOption) *Route {
+	return s.Handle(http.MethodHead, path, controller, opts...)
+}
+
+func (s *Server) OnPost(path string, controller Controller, opts ...RouteOption) *Route {
+	return s.Handle(http.MethodPost, path, controller, opts...)
+}
+
+func (s *Server) OnPut(path string, controller Controller, opts ...RouteOption) *Route {
+	return s.Handle(http.MethodPut, path, controller, opts...)
+}
+
+func (s *Server) OnPatch(path string, controller Controller, opts ...RouteOption) *Route {
+	return s.Handle(http.MethodPatch, path, controller, opts...)
+}
+
+func (s *Server) OnDelete(path string, controller Controller, opts ...RouteOption) *Route {
+	return s.Handle(http.MethodDelete, path, controller, opts...)
+}
+
+func (s *Server) OnOptions(path string, controller Controller, opts ...RouteOption) *Route {
+	return s.Handle(http.MethodOptions, path, controller, opts...)
+}
+
+func (s *Server) OnAny(path string, controller Controller, opts ...RouteOption) Routes {
+	return s.router.onAny(path, controller, nil, opts)
 }
 
-func (s *Server) OnPost(path string, controller Controller) {
-	s.router.OnPost(path, controller)
+// HandleFunc registers a net/http handler function, see HandlerController.
+func (s *Server) HandleFunc(method, path string, handler http.HandlerFunc, opts ...RouteOption) *Route {
+	return s.Handle(method, path, &HandlerController{Handler: handler}, opts...)
 }
 
-func (s *Server) OnPut(path string, controller Controller) {
-	s.router.OnPut(path, controller)
+// Mount serves every path below prefix with a net/http handler, such as
+// net/http/pprof or expvar. When handler is another Server its routes are
+// registered below prefix instead, so that they run through this server's
+// middlewares only once.
+func (s *Server) Mount(prefix string, handler http.Handler) Routes {
+	if other, ok := handler.(*Server); ok {
+		return s.router.MountRouter(prefix, &other.router)
+	}
+	return s.router.Mount(prefix, handler)
+}
+
+// ReplaceRoute swaps the controller of the route registered for method and
+// path, or registers it. Routes can be added, replaced and removed while the
+// server is serving.
+func (s *Server) ReplaceRoute(method, path string, controller Controller, opts ...RouteOption) *Route {
+	return s.router.Replace(method, path, controller, opts...)
+}
+
+// RemoveRoute unregisters the route registered for method and path, it
+// reports whether there was one.
+func (s *Server) RemoveRoute(method, path string) bool {
+	return s.router.Remove(method, path)
 }
 
-func (s *Server) OnDelete(path string, controller Controller) {
-	s.router.OnDelete(path, controller)
+// Group returns a route group whose routes share the prefix and run the given
+// middlewares between the server-wide queue and the controller.
+func (s *Server) Group(prefix string, middlewares ...Middleware) *Group {
+	return newGroup(&s.router, prefix, NewMiddlewareQueue(middlewares...).Clone())
 }
 
-func (s *Server) Static(path, realPath string) {
+// Host returns the root group of the router serving requests for the host
+// pattern, either a plain host such as api.example.com or a wildcard such as
+// *.example.com whose subdomain is captured as the HostParam router param.
+// Requests matching no host pattern are served by the default router.
+func (s *Server) Host(pattern string) *Group {
+	router := s.hosts.add(pattern, func() *Router {
+		router := NewRouter()
+		router.IgnoreCase(s.router.ignoreCase)
+		router.host = normalizeHost(pattern)
+		return &router
+	})
+	return newGroup(router, "", nil)
+}
+
+func (s *Server) Static(path, realPath string) *Route {
 	if !filepath.IsAbs(realPath) {
 		realPath = filepath.Join(env.RootDir(), realPath)
 	}
@@ -136,35 +246,79 @@ func (s *Server) Static(path, realPath string) {
 		panic(fmt.Sprintf("path err %v", err))
 	}
 
-	filepath.Walk(realPath, func(p string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
+	return s.router.Static(path, &StaticController{
+		Path: realPath,
+	})
+}
 
-		relPath, err := filepath.Rel(realPath, p)
-		if err != nil {
-			return err
-		}
-		tmpPath := filepath.Join(path, relPath)
-		if info.IsDir() {
-			if !strings.HasSuffix(p, "/") {
-				p = p + "/"
-			}
+// NotFound replaces the controller answering requests that match no route.
+// It runs through the middleware queue with the status preset to 404.
+func (s *Server) NotFound(controller Controller) {
+	s.notFound = controller
+}
+
+// MethodNotAllowed replaces the controller answering requests whose path is
+// only routable with other methods. It runs through the middleware queue with
+// the status preset to 405 and the Allow header set.
+func (s *Server) MethodNotAllowed(controller Controller) {
+	s.methodNotAllowed = controller
+}
+
+// ErrorHandler replaces the handler answering the errors requests fail with,
+// ResponseErrorHandler by default, e.g. with ProblemErrorHandler.
+func (s *Server) ErrorHandler(h ErrorHandler) {
+	s.errorHandler = h
+}
+
+// URLFor builds the path of the route named name, see Route.Name.
+func (s *Server) URLFor(name string, params map[string]string, query url.Values) (string, error) {
+	for _, router := range s.hosts.routers() {
+		if router.hasName(name) {
+			return router.URLFor(name, params, query)
 		}
+	}
+	return s.router.URLFor(name, params, query)
+}
 
-		s.router.Static(tmpPath, &StaticController{
-			Path: p,
-		})
+// Routes describes every route registered on the server, those of the
+// default router first.
+func (s *Server) Routes() []RouteInfo {
+	routes := s.router.Routes()
+	for _, router := range s.hosts.routers() {
+		routes = append(routes, router.Routes()...)
+	}
+	return routes
+}
 
-		return nil
+// ExposeRoutes serves the route table as JSON on a GET path, it is meant for
+// debugging and should not be exposed publicly.
+func (s *Server) ExposeRoutes(path string) *Route {
+	return s.router.OnGet(path, &RoutesController{
+		Routes: s.Routes,
 	})
 }
 
+func (s *Server) printRoutes() {
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "HOST\tMETHOD\tPATTERN\tVERSION\tNAME\tCONTROLLER\tMIDDLEWARES")
+	for _, info := range s.Routes() {
+		host := info.Host
+		if host == "" {
+			// served for any host
+			host = "*"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", host, info.Method, info.Pattern, info.Version, info.Name, info.Controller, strings.Join(info.Middlewares, ","))
+	}
+	tw.Flush()
+}
+
 func (s *Server) ServeHTTP(w http.ResponseWriter, req *http.Request) {
-	ctx := WithContext(req.Context())
+	gcx := acquireContext()
+	defer releaseContext(gcx)
+
+	ctx := context.WithValue(req.Context(), globalContextKey, gcx)
 	ctx = logger.WithLoggerContext(ctx)
-	gcx := GetContext(ctx)
-	gcx.SetContextOptions(WithRequest(req), WithResponseWriter(w))
+	gcx.SetContextOptions(WithRequest(req), WithResponseWriter(w), WithErrorHandler(s.errorHandler))
 
 	mq := s.mq.Clone()
 
@@ -172,18 +326,82 @@ func (s *Server) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 		mq.Use(s.rateLimiter.RateLimiterAsMiddleware())
 	}
 
-	controller, params, ok := s.router.Route(req.Method, req.URL.Path)
-	if !ok {
-		w.WriteHeader(http.StatusNotFound)
-		return
+	controller, middlewares := s.route(w, req, gcx)
+
+	instance, release := newController(controller)
+
+	mq.Use(middlewares...)
+	mq.Use(controllerAsMiddleware(instance))
+
+	mq.Next(ctx)
+
+	// a detached request may still be served by the controller
+	if !gcx.detached.Load() {
+		release()
 	}
-	if params != nil {
-		gcx.SetContextOptions(WithRouterParams(params))
+}
+
+// route picks the controller for req, and the response status and headers
+// when routing fails.
+func (s *Server) route(w http.ResponseWriter, req *http.Request, gcx *Context) (Controller, MiddlewareQueue) {
+	router := &s.router
+	var subdomain string
+	if hr, sub, ok := s.hosts.match(req.Host); ok {
+		router, subdomain = hr, sub
 	}
 
-	cloned := CloneController(controller)
+	path := req.URL.Path
+	if canonical := cleanPath(path); canonical != path {
+		switch s.pathPolicy {
+		case env.PathStrict:
+			gcx.status = http.StatusNotFound
+			return s.notFound, nil
+		case env.PathRedirect:
+			u := url.URL{Path: canonical, RawQuery: req.URL.RawQuery}
+			w.Header().Set("Location", u.String())
+			gcx.status = s.redirectCode
+			return &BaseController{}, nil
+		default:
+			path = canonical
+		}
+	}
 
-	mq.Use(controllerAsMiddleware(cloned))
+	version := requestVersion(req)
+	if version == "" {
+		version = s.defaultVersion
+	}
+	version = normalizeVersion(version)
+
+	// a path whose routes all miss the version asked for is answered like
+	// one without routes of the method
+	if n := router.lookup(req.Method, path, &gcx.routerParams); n != nil {
+		if len(n.versions) > 0 {
+			// the version may come from the Accept media type as well
+			w.Header().Add("Vary", "Accept-Version")
+			w.Header().Add("Vary", "Accept")
+		}
+		if rt := n.pick(version); rt != nil {
+			if subdomain != "" {
+				gcx.routerParams = append(gcx.routerParams, Param{Key: HostParam, Value: subdomain})
+			}
+			gcx.route = rt
+			return rt.controller, rt.middlewares
+		}
+		gcx.routerParams = gcx.routerParams[:0]
+	}
 
-	mq.Next(ctx)
+	allowed := router.AllowedVersion(path, version)
+	switch {
+	case len(allowed) == 0:
+		gcx.status = http.StatusNotFound
+		return s.notFound, nil
+	case req.Method == http.MethodOptions:
+		w.Header().Set("Allow", strings.Join(allowed, ", "))
+		gcx.status = http.StatusNoContent
+		return &BaseController{}, nil
+	default:
+		w.Header().Set("Allow", strings.Join(allowed, ", "))
+		gcx.status = http.StatusMethodNotAllowed
+		return s.methodNotAllowed, nil
+	}
 }
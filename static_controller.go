@@ -2,15 +2,24 @@ package golitekit
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"net/http"
 	"net/url"
 	"os"
+	"path"
 	"path/filepath"
 	"sort"
 )
 
+const (
+	StaticPathParam = "filepath"
+)
+
+// StaticController serves the file or directory at Path. When the route
+// captures a StaticPathParam, it is resolved relative to Path instead.
 type StaticController struct {
 	BaseController
 	Path string
@@ -21,8 +30,19 @@ func (c *StaticController) Serve(ctx context.Context) error {
 }
 
 func (c *StaticController) Handle(ctx context.Context) error {
-	f, err := os.Open(c.Path)
-	if err != nil {
+	name := c.Path
+	if rel := c.RouterParamString(StaticPathParam, ""); rel != "" {
+		// cleaning a rooted path drops any ".." that would escape Path
+		name = filepath.Join(c.Path, filepath.FromSlash(path.Clean("/"+rel)))
+	}
+
+	f, err := os.Open(name)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		return NotFound("").Wrap(err)
+	case errors.Is(err, fs.ErrPermission):
+		return Forbidden("").Wrap(err)
+	case err != nil:
 		return err
 	}
 	defer f.Close()
@@ -46,7 +66,7 @@ func (c *StaticController) Handle(ctx context.Context) error {
 		return err
 	}
 
-	ext := filepath.Ext(c.Path)
+	ext := filepath.Ext(name)
 	c.gcx.ServeFile(ext, data)
 
 	return nil
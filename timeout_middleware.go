@@ -2,13 +2,20 @@ package golitekit
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"github/hsj/GoLiteKit/env"
 	"log"
+	"time"
 )
 
+var errTimeout = errors.New("timeout")
+
 func TimeoutMiddleware(ctx context.Context, queue MiddlewareQueue) error {
-	timeout := env.WriteTimeout()
+	return serveWithTimeout(ctx, queue, env.WriteTimeout())
+}
+
+func serveWithTimeout(ctx context.Context, queue MiddlewareQueue, timeout time.Duration) error {
 	if timeout < 1 {
 		return queue.Next(ctx)
 	}
@@ -16,10 +23,10 @@ func TimeoutMiddleware(ctx context.Context, queue MiddlewareQueue) error {
 	ctx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
 
-	doneChan := make(chan struct{}, 1)
+	// buffered and never closed, the handler goroutine may send after a
+	// timeout without blocking
+	doneChan := make(chan error, 1)
 	panicChan := make(chan any, 1)
-	defer close(doneChan)
-	defer close(panicChan)
 
 	go func() {
 		defer func() {
@@ -35,14 +42,12 @@ func TimeoutMiddleware(ctx context.Context, queue MiddlewareQueue) error {
 			}
 		}()
 
+		var err error
 		select {
 		case <-ctx.Done():
 			return
 		default:
-			err := queue.Next(ctx)
-			if err != nil {
-				log.Printf("%v", err)
-			}
+			err = queue.Next(ctx)
 		}
 
 		select {
@@ -51,7 +56,7 @@ func TimeoutMiddleware(ctx context.Context, queue MiddlewareQueue) error {
 		default:
 		}
 
-		doneChan <- struct{}{}
+		doneChan <- err
 	}()
 
 	select {
@@ -60,8 +65,10 @@ func TimeoutMiddleware(ctx context.Context, queue MiddlewareQueue) error {
 		return fmt.Errorf("panic: %v", p)
 	case <-ctx.Done():
 		log.Print("timeout")
-		return fmt.Errorf("timeout")
-	case <-doneChan:
-		return nil
+		// the handler goroutine may still use the request context
+		GetContext(ctx).detach()
+		return ServiceUnavailable("").Wrap(errTimeout)
+	case err := <-doneChan:
+		return err
 	}
 }
@@ -1,79 +1,207 @@
 package golitekit
 
 import (
-	"testing"
+	"fmt"
+	"strings"
 )
 
-type TestController struct {
-	BaseController
+// Trie is the segment trie the Router matched paths with before the radix
+// tree, it is kept as the baseline of BenchmarkTrieGet.
+type Trie struct {
+	root *Node
+}
 
-	value string
+type Node struct {
+	children map[string]*Node
+	// constrained wild children come first, so /item/:id<int> is tried
+	// before /item/:name
+	wilds      []*Node
+	catchAll   *Node
+	route      *Route
+	word       string
+	constraint *paramConstraint
+}
+
+func NewTrie() *Trie {
+	return &Trie{
+		root: newNode(),
+	}
 }
 
-func TestAddGet(t *testing.T) {
-	cases := []struct {
-		path       string
-		controller Controller
-	}{
-		{"/home", &TestController{}},
-		{"/about", &TestController{}},
-		{"/user/:id", &TestController{}},
-		{"/user/:id/aaa", &TestController{value: "aaa"}},
-		{"/user/:id/bbb", &TestController{value: "bbb"}},
+func newNode() *Node {
+	return &Node{
+		children: make(map[string]*Node),
 	}
+}
+
+// These paths are identical, duplicate paths are not allowed
+// /user/:id/name
+// /user/:status/name
+//
+// Wild segments with different constraints may share a position
+// /item/:id<int>
+// /item/:name
+//
+// Static, wild and catch-all segments may share a position, a catch-all
+// such as /files/*filepath must be the last segment.
+func (t *Trie) Add(path string, controller Controller) {
+	t.add(path, &Route{
+		path:       path,
+		controller: controller,
+	})
+}
 
-	trie := NewTrie()
+func (t *Trie) add(path string, rt *Route) {
+	segments := splitPath(path)
+	node := t.root
 
-	for _, c := range cases {
-		trie.Add(c.path, c.controller)
+	for i, seg := range segments {
+		w := seg.word
+		switch {
+		case isCatchAllWord(w):
+			if i != len(segments)-1 {
+				panic("catch-all must be the last segment: " + path)
+			}
+			if node.catchAll == nil {
+				node.catchAll = newNode()
+				node.catchAll.word = w[1:]
+			} else if node.catchAll.word != w[1:] {
+				panic("duplicate path: " + path)
+			}
+			node = node.catchAll
+		case isWildWord(w):
+			name, constraint, err := parseWildWord(w)
+			if err != nil {
+				panic(fmt.Sprintf("%v in path: %s", err, path))
+			}
+			node = node.addWild(name, constraint, path)
+		default:
+			child, ok := node.children[w]
+			if !ok {
+				child = newNode()
+				child.word = w
+				node.children[w] = child
+			}
+			node = child
+		}
 	}
+	if node.route != nil {
+		panic("duplicate path: " + path)
+	}
+	node.route = rt
+}
 
-	for _, c := range cases {
-		controller, _, _ := trie.Get(c.path)
-		if controller == nil {
-			t.Errorf("controller not found for path %s", c.path)
+func (n *Node) addWild(name string, constraint *paramConstraint, path string) *Node {
+	for _, child := range n.wilds {
+		if child.constraint.String() != constraint.String() {
+			continue
 		}
-		if controller != c.controller {
-			t.Errorf("wrong controller found for path %s", c.path)
+		if child.word != name {
+			panic("duplicate path: " + path)
 		}
+		return child
 	}
+
+	child := newNode()
+	child.word = name
+	child.constraint = constraint
+
+	i := len(n.wilds)
+	if constraint != nil {
+		for i > 0 && n.wilds[i-1].constraint == nil {
+			i--
+		}
+	}
+	n.wilds = append(n.wilds, nil)
+	copy(n.wilds[i+1:], n.wilds[i:])
+	n.wilds[i] = child
+	return child
 }
 
-func TestWildPath(t *testing.T) {
-	trie := NewTrie()
-	trie.Add("/user/:id", &TestController{})
-	if c, _, ok := trie.Get("/user/:id"); !ok || c == nil {
-		t.Errorf("controller not found for path %s", "/user/:id")
+// Add path /user/:id/name
+// Get path /user/123456/name
+// params: id = 123456
+//
+// Add path /files/*filepath
+// Get path /files/css/main.css
+// params: filepath = css/main.css
+//
+// Static segments are preferred over wild ones and wild ones over catch-all,
+// when a branch fails to match the whole path the next one is tried.
+func (t *Trie) Get(path string) (Controller, map[string]string, bool) {
+	rt, params, ok := t.match(path)
+	if !ok {
+		return nil, nil, false
 	}
-	if c, _, ok := trie.Get("/user/:name"); !ok || c == nil {
-		t.Errorf("controller not found for path %s", "/user/:name")
+	return rt.controller, params, true
+}
+
+func (t *Trie) match(path string) (*Route, map[string]string, bool) {
+	m := matcher{
+		path: path,
+	}
+	m.segments = splitPath(path)
+	node := m.match(t.root, 0)
+	if node == nil {
+		return nil, nil, false
 	}
+
+	params := make(map[string]string, len(m.params))
+	for _, p := range m.params {
+		params[p.key] = p.value
+	}
+	return node.route, params, true
 }
 
-func TestDuplicatePath(t *testing.T) {
-	defer func() {
-		if r := recover(); r != nil {
-			if r != "duplicate path: /user/:name" {
-				t.Errorf("Expected panic value 'duplicate path: /user/:name', but got %v", r)
-			}
-			t.Logf("Recovered: %v", r)
-		}
-	}()
+type matchedParam struct {
+	key   string
+	value string
+}
 
-	trie := NewTrie()
-	trie.Add("/user/:id", &TestController{value: "id"})
-	trie.Add("/user/:name", &TestController{value: "name"})
-	t.Errorf("Expected panic, but test completed without panic")
+type matcher struct {
+	path     string
+	segments []segment
+	params   []matchedParam
 }
 
-func TestWildParams(t *testing.T) {
-	trie := NewTrie()
-	trie.Add("/user/:id/name", &TestController{})
-	c, params, _ := trie.Get("/user/xiaoming/name")
-	if c == nil {
-		t.Errorf("controller not found for path %s", "/user/xiaoming/name")
+func (m *matcher) match(node *Node, i int) *Node {
+	if i == len(m.segments) {
+		if node.route != nil {
+			return node
+		}
+		// a catch-all also matches an empty remainder
+		if node.catchAll != nil {
+			m.params = append(m.params, matchedParam{node.catchAll.word, ""})
+			return node.catchAll
+		}
+		return nil
 	}
-	if params["id"] != "xiaoming" {
-		t.Errorf("wrong params found for path %s", "/user/xiaoming/name")
+
+	seg := m.segments[i]
+	word := m.path[seg.start : seg.start+len(seg.word)]
+	if child, ok := node.children[seg.word]; ok {
+		if found := m.match(child, i+1); found != nil {
+			return found
+		}
 	}
+
+	for _, wild := range node.wilds {
+		if wild.constraint != nil && !wild.constraint.match(word) {
+			continue
+		}
+		n := len(m.params)
+		m.params = append(m.params, matchedParam{wild.word, word})
+		if found := m.match(wild, i+1); found != nil {
+			return found
+		}
+		m.params = m.params[:n]
+	}
+
+	if node.catchAll != nil {
+		rest := strings.TrimRight(m.path[seg.start:], "/")
+		m.params = append(m.params, matchedParam{node.catchAll.word, rest})
+		return node.catchAll
+	}
+
+	return nil
 }
@@ -0,0 +1,153 @@
+package golitekit
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github/hsj/GoLiteKit/env"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// UploadLimiter is implemented by controllers restricting the files of their
+// multipart forms, next to RequestSizeLimiter. Parts larger than
+// MaxMemorySize are spooled to temporary files, which Finalize removes.
+type UploadLimiter interface {
+	// MaxFileSize limits the size of each file, it is unlimited when not
+	// positive
+	MaxFileSize() int64
+	// AllowedFileTypes are the media types files may have, such as image/png
+	// or image/*, detected from their content. Any type is allowed when empty.
+	AllowedFileTypes() []string
+}
+
+var uploadExtPattern = regexp.MustCompile(`^\.[a-z0-9]{1,10}$`)
+
+// UploadedFiles returns the files of a multipart form field, checked against
+// the UploadLimiter of the controller. The files violating it are reported as
+// FieldErrors.
+func (c *BaseController) UploadedFiles(key string) ([]*multipart.FileHeader, error) {
+	if c.request.MultipartForm == nil {
+		return nil, nil
+	}
+	files := c.request.MultipartForm.File[key]
+	limiter, ok := c.gcx.controller.(UploadLimiter)
+	if !ok {
+		return files, nil
+	}
+
+	var errs FieldErrors
+	for _, fh := range files {
+		if err := checkUpload(fh, limiter); err != nil {
+			errs = append(errs, &FieldError{Field: key, Source: "form", Key: key, Msg: err.Error()})
+		}
+	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	return files, nil
+}
+
+// UploadedFile returns the first file of a multipart form field, see
+// UploadedFiles, or http.ErrMissingFile when there is none.
+func (c *BaseController) UploadedFile(key string) (*multipart.FileHeader, error) {
+	files, err := c.UploadedFiles(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, http.ErrMissingFile
+	}
+	return files[0], nil
+}
+
+// SaveUploadedFile saves an uploaded file into the upload directory, see
+// env.UploadDir, and returns its path. The file is named randomly, keeping
+// the extension of the client's file name only when it is plain
+// alphanumeric, so that client names never reach the file system.
+func (c *BaseController) SaveUploadedFile(fh *multipart.FileHeader) (string, error) {
+	return saveUploadedFile(fh, env.UploadDir())
+}
+
+func checkUpload(fh *multipart.FileHeader, limiter UploadLimiter) error {
+	if max := limiter.MaxFileSize(); max > 0 && fh.Size > max {
+		return fmt.Errorf("file %q is larger than %d bytes", fh.Filename, max)
+	}
+
+	allowed := limiter.AllowedFileTypes()
+	if len(allowed) == 0 {
+		return nil
+	}
+	mt, err := sniffFileType(fh)
+	if err != nil {
+		return err
+	}
+	for _, pattern := range allowed {
+		if ok, _ := path.Match(pattern, mt); ok {
+			return nil
+		}
+	}
+	return fmt.Errorf("file %q of type %s is not one of %s", fh.Filename, mt, strings.Join(allowed, ", "))
+}
+
+// sniffFileType detects the media type of an uploaded file from its first
+// bytes, ignoring the type the client claims.
+func sniffFileType(fh *multipart.FileHeader) (string, error) {
+	f, err := fh.Open()
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	buf := make([]byte, 512)
+	n, err := io.ReadFull(f, buf)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return "", err
+	}
+	mt, _, err := mime.ParseMediaType(http.DetectContentType(buf[:n]))
+	return mt, err
+}
+
+func saveUploadedFile(fh *multipart.FileHeader, dir string) (string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+
+	var name [16]byte
+	if _, err := rand.Read(name[:]); err != nil {
+		return "", err
+	}
+	ext := strings.ToLower(path.Ext(strings.ReplaceAll(fh.Filename, `\`, "/")))
+	if !uploadExtPattern.MatchString(ext) {
+		ext = ""
+	}
+	dst := filepath.Join(dir, hex.EncodeToString(name[:])+ext)
+
+	src, err := fh.Open()
+	if err != nil {
+		return "", err
+	}
+	defer src.Close()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return "", err
+	}
+	_, err = io.Copy(out, src)
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(dst)
+		return "", err
+	}
+	return dst, nil
+}
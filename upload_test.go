@@ -0,0 +1,117 @@
+package golitekit
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var pngHeader = "\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"
+
+type uploadController struct {
+	BaseController
+
+	Dir   string
+	Files func([]*multipart.FileHeader)
+}
+
+func (c *uploadController) MaxMemorySize() int64 {
+	return 1
+}
+
+func (c *uploadController) MaxFileSize() int64 {
+	return 32
+}
+
+func (c *uploadController) AllowedFileTypes() []string {
+	return []string{"image/png", "image/gif"}
+}
+
+func (c *uploadController) Serve(ctx context.Context) error {
+	files, err := c.UploadedFiles("image")
+	if err != nil {
+		return err
+	}
+	c.Files(files)
+	var names []string
+	for _, fh := range files {
+		name, err := saveUploadedFile(fh, c.Dir)
+		if err != nil {
+			return err
+		}
+		names = append(names, filepath.Base(name))
+	}
+	c.ServeRawData(strings.Join(names, ","))
+	return nil
+}
+
+func multipartBody(t *testing.T, files map[string]string) (*bytes.Buffer, string) {
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	for name, content := range files {
+		fw, err := mw.CreateFormFile("image", name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fw.Write([]byte(content))
+	}
+	mw.Close()
+	return &buf, mw.FormDataContentType()
+}
+
+func TestUpload(t *testing.T) {
+	dir := t.TempDir()
+	var uploaded []*multipart.FileHeader
+	s := newTestServer()
+	s.OnPost("/upload", &uploadController{
+		Dir:   dir,
+		Files: func(files []*multipart.FileHeader) { uploaded = files },
+	})
+
+	cases := []struct {
+		files  map[string]string
+		status int
+	}{
+		{map[string]string{"../../a.PNG": pngHeader, "b.gif": "GIF89a"}, http.StatusOK},
+		{map[string]string{"fake.png": "plain text pretending"}, http.StatusBadRequest},
+		{map[string]string{"big.png": pngHeader + strings.Repeat("x", 32)}, http.StatusBadRequest},
+	}
+	for _, c := range cases {
+		body, ct := multipartBody(t, c.files)
+		req := httptest.NewRequest(http.MethodPost, "/upload", body)
+		req.Header.Set("Content-Type", ct)
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, req)
+		if w.Code != c.status {
+			t.Errorf("%v: expected status %d, got %d %s", c.files, c.status, w.Code, w.Body.String())
+		}
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil || len(entries) != 2 {
+		t.Fatalf("Expected 2 saved files, got %v %v", entries, err)
+	}
+	for _, e := range entries {
+		if ext := filepath.Ext(e.Name()); ext != ".png" && ext != ".gif" || len(e.Name()) != 32+len(ext) {
+			t.Errorf("Unexpected saved file name %s", e.Name())
+		}
+	}
+
+	// parts beyond MaxMemorySize are spooled to disk and removed by Finalize
+	if len(uploaded) != 2 {
+		t.Fatalf("Expected 2 uploaded files, got %d", len(uploaded))
+	}
+	if f, err := uploaded[0].Open(); err == nil {
+		f.Close()
+		t.Errorf("Expected the spooled file to be removed")
+	} else if !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Unexpected error %v", err)
+	}
+}
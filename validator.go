@@ -0,0 +1,361 @@
+package golitekit
+
+import (
+	"fmt"
+	"net/mail"
+	"net/url"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// Rule reports whether field satisfies a validate tag rule. param is the
+// text after the "=" of the rule, parent is the struct holding field, for
+// rules comparing fields.
+type Rule func(field reflect.Value, param string, parent reflect.Value) bool
+
+var (
+	rulesMu     sync.RWMutex
+	customRules = map[string]Rule{}
+)
+
+// RegisterRule adds a rule usable in validate tags, it replaces a builtin rule
+// of the same name. It panics for required and omitempty, which decide
+// whether the rules after them run and cannot be replaced.
+func RegisterRule(name string, rule Rule) {
+	if name == "required" || name == "omitempty" {
+		panic(fmt.Sprintf("validate: rule %q cannot be replaced", name))
+	}
+	rulesMu.Lock()
+	defer rulesMu.Unlock()
+	customRules[name] = rule
+}
+
+// ValidateStruct checks the struct v points to against the validate tags of
+// its fields, such as
+//
+//	Name  string   `validate:"required,min=1,max=64"`
+//	Email string   `validate:"omitempty,email"`
+//	Role  string   `validate:"oneof=admin user"`
+//	Tags  []string `validate:"max=8"`
+//	Again string   `validate:"eqfield=Password"`
+//
+// min, max, len, eq, ne, gt, gte, lt and lte compare numbers and durations by
+// value, and strings, slices and maps by length. eqfield, nefield, gtfield,
+// gtefield, ltfield and ltefield compare a field with another field of the
+// same struct. omitempty skips the rules after it when the field is zero, the
+// rules of a nil pointer are skipped except required. Untagged struct fields
+// are checked recursively. Every failed rule is reported as a FieldError,
+// ValidateStruct returns them all together as FieldErrors.
+func ValidateStruct(v any) error {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return fmt.Errorf("validate: nil %T", v)
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return fmt.Errorf("validate: %T is not a struct", v)
+	}
+
+	var errs FieldErrors
+	if err := validateStruct(rv, "", &errs); err != nil {
+		return err
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// validateStruct descends into the fields binder.walk binds, including the
+// exported fields of unexported embedded structs.
+func validateStruct(v reflect.Value, prefix string, errs *FieldErrors) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() && !f.Anonymous {
+			continue
+		}
+		fv := v.Field(i)
+		name := prefix + f.Name
+
+		tag, ok := f.Tag.Lookup("validate")
+		if !ok || tag == "-" {
+			ft := fv
+			if ft.Kind() == reflect.Pointer && !ft.IsNil() {
+				if !f.IsExported() {
+					continue
+				}
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct && !isScalarType(ft.Type()) {
+				if f.Anonymous {
+					name = prefix
+				} else {
+					name += "."
+				}
+				if err := validateStruct(ft, name, errs); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		if !f.IsExported() {
+			continue
+		}
+
+		if err := validateField(fv, v, name, tag, errs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateField(fv, parent reflect.Value, name, tag string, errs *FieldErrors) error {
+	for _, r := range strings.Split(tag, ",") {
+		rule, param, _ := strings.Cut(strings.TrimSpace(r), "=")
+		switch rule {
+		case "":
+			continue
+		case "omitempty":
+			if fv.IsZero() {
+				return nil
+			}
+			continue
+		case "required":
+			if fv.IsZero() {
+				*errs = append(*errs, &FieldError{Field: name, Rule: rule, Msg: "is required"})
+				return nil
+			}
+			continue
+		}
+
+		field := fv
+		if field.Kind() == reflect.Pointer {
+			if field.IsNil() {
+				return nil
+			}
+			field = field.Elem()
+		}
+
+		check, ok := lookupRule(rule)
+		if !ok {
+			return fmt.Errorf("validate: unknown rule %q on field %s", rule, name)
+		}
+		if !check(field, param, parent) {
+			*errs = append(*errs, &FieldError{Field: name, Rule: rule, Msg: ruleMessage(field, rule, param)})
+			return nil
+		}
+	}
+	return nil
+}
+
+func lookupRule(name string) (Rule, bool) {
+	rulesMu.RLock()
+	rule, ok := customRules[name]
+	rulesMu.RUnlock()
+	if ok {
+		return rule, true
+	}
+	if rule, ok := builtinRules[name]; ok {
+		return rule, true
+	}
+	if match, ok := builtinConstraints[name]; ok {
+		return stringRule(match), true
+	}
+	return nil, false
+}
+
+var builtinRules = map[string]Rule{
+	"min": compareRule(func(c int) bool { return c >= 0 }),
+	"max": compareRule(func(c int) bool { return c <= 0 }),
+	"len": compareRule(func(c int) bool { return c == 0 }),
+	"eq":  equalRule(true),
+	"ne":  equalRule(false),
+	"gt":  compareRule(func(c int) bool { return c > 0 }),
+	"gte": compareRule(func(c int) bool { return c >= 0 }),
+	"lt":  compareRule(func(c int) bool { return c < 0 }),
+	"lte": compareRule(func(c int) bool { return c <= 0 }),
+	"oneof": func(field reflect.Value, param string, _ reflect.Value) bool {
+		return slices.Contains(strings.Fields(param), fmt.Sprint(field.Interface()))
+	},
+	"email": func(field reflect.Value, _ string, _ reflect.Value) bool {
+		if field.Kind() != reflect.String {
+			return false
+		}
+		addr, err := mail.ParseAddress(field.String())
+		return err == nil && addr.Address == field.String()
+	},
+	"url": func(field reflect.Value, _ string, _ reflect.Value) bool {
+		if field.Kind() != reflect.String {
+			return false
+		}
+		u, err := url.ParseRequestURI(field.String())
+		return err == nil && u.Scheme != "" && u.Host != ""
+	},
+	"numeric":  stringRule(builtinConstraints["float"]),
+	"eqfield":  fieldRule(func(c int) bool { return c == 0 }),
+	"nefield":  fieldRule(func(c int) bool { return c != 0 }),
+	"gtfield":  fieldRule(func(c int) bool { return c > 0 }),
+	"gtefield": fieldRule(func(c int) bool { return c >= 0 }),
+	"ltfield":  fieldRule(func(c int) bool { return c < 0 }),
+	"ltefield": fieldRule(func(c int) bool { return c <= 0 }),
+}
+
+func stringRule(match func(string) bool) Rule {
+	return func(field reflect.Value, _ string, _ reflect.Value) bool {
+		return field.Kind() == reflect.String && match(field.String())
+	}
+}
+
+// compareRule compares field with param, see size.
+func compareRule(ok func(c int) bool) Rule {
+	return func(field reflect.Value, param string, _ reflect.Value) bool {
+		c, valid := compareParam(field, param)
+		return valid && ok(c)
+	}
+}
+
+func equalRule(equal bool) Rule {
+	return func(field reflect.Value, param string, _ reflect.Value) bool {
+		if field.Kind() == reflect.String {
+			return (field.String() == param) == equal
+		}
+		c, valid := compareParam(field, param)
+		return valid && (c == 0) == equal
+	}
+}
+
+// fieldRule compares field with the field of parent named by param.
+func fieldRule(ok func(c int) bool) Rule {
+	return func(field reflect.Value, param string, parent reflect.Value) bool {
+		other := parent.FieldByName(param)
+		if !other.IsValid() {
+			return false
+		}
+		if other.Kind() == reflect.Pointer {
+			if other.IsNil() {
+				return false
+			}
+			other = other.Elem()
+		}
+		c, valid := compareValues(field, other)
+		return valid && ok(c)
+	}
+}
+
+// size returns the value of numbers and the length of strings and
+// collections, the measure min and max compare.
+func size(v reflect.Value) (float64, bool) {
+	switch v.Kind() {
+	case reflect.String:
+		return float64(utf8.RuneCountInString(v.String())), true
+	case reflect.Slice, reflect.Map, reflect.Array:
+		return float64(v.Len()), true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), true
+	}
+	return 0, false
+}
+
+func compareParam(field reflect.Value, param string) (int, bool) {
+	var p float64
+	if field.Type() == durationType {
+		d, err := time.ParseDuration(param)
+		if err != nil {
+			return 0, false
+		}
+		p = float64(d)
+	} else {
+		f, err := strconv.ParseFloat(param, 64)
+		if err != nil {
+			return 0, false
+		}
+		p = f
+	}
+	s, ok := size(field)
+	if !ok {
+		return 0, false
+	}
+	return compareFloat(s, p), true
+}
+
+func compareValues(a, b reflect.Value) (int, bool) {
+	if a.Type() == timeType && b.Type() == timeType {
+		return a.Interface().(time.Time).Compare(b.Interface().(time.Time)), true
+	}
+	if a.Kind() == reflect.String && b.Kind() == reflect.String {
+		return strings.Compare(a.String(), b.String()), true
+	}
+	x, ok := size(a)
+	y, ok2 := size(b)
+	if !ok || !ok2 {
+		if a.Type() == b.Type() && a.Comparable() && a.Equal(b) {
+			return 0, true
+		}
+		return 0, false
+	}
+	return compareFloat(x, y), true
+}
+
+func compareFloat(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func ruleMessage(field reflect.Value, rule, param string) string {
+	subject := "must be"
+	switch field.Kind() {
+	case reflect.String, reflect.Slice, reflect.Map, reflect.Array:
+		subject = "length must be"
+	}
+	switch rule {
+	case "min", "gte":
+		return fmt.Sprintf("%s at least %s", subject, param)
+	case "max", "lte":
+		return fmt.Sprintf("%s at most %s", subject, param)
+	case "gt":
+		return fmt.Sprintf("%s greater than %s", subject, param)
+	case "lt":
+		return fmt.Sprintf("%s less than %s", subject, param)
+	case "len":
+		return fmt.Sprintf("%s %s", subject, param)
+	case "eq":
+		return "must be " + param
+	case "ne":
+		return "must not be " + param
+	case "oneof":
+		return "must be one of " + strings.Join(strings.Fields(param), ", ")
+	case "eqfield":
+		return "must equal " + param
+	case "nefield":
+		return "must differ from " + param
+	case "gtfield":
+		return "must be greater than " + param
+	case "gtefield":
+		return "must be at least " + param
+	case "ltfield":
+		return "must be less than " + param
+	case "ltefield":
+		return "must be at most " + param
+	}
+	if param != "" {
+		return fmt.Sprintf("must satisfy %s=%s", rule, param)
+	}
+	return "must be a valid " + rule
+}
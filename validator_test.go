@@ -0,0 +1,164 @@
+package golitekit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type signupRequest struct {
+	Name     string        `validate:"required,min=2,max=8"`
+	Email    string        `validate:"omitempty,email"`
+	Role     string        `validate:"oneof=admin user"`
+	Age      *int          `validate:"omitempty,gte=18"`
+	Tags     []string      `validate:"max=2"`
+	Password string        `validate:"required"`
+	Again    string        `validate:"eqfield=Password"`
+	Start    time.Time     `validate:"required"`
+	End      time.Time     `validate:"gtfield=Start"`
+	Timeout  time.Duration `validate:"lte=1m"`
+	Code     string        `validate:"omitempty,even"`
+	Address  struct {
+		City string `validate:"required,alpha"`
+	}
+}
+
+func TestValidateStruct(t *testing.T) {
+	RegisterRule("even", func(field reflect.Value, _ string, _ reflect.Value) bool {
+		return len(field.String())%2 == 0
+	})
+
+	now := time.Now()
+	age := 20
+	valid := signupRequest{
+		Name:     "gopher",
+		Email:    "gopher@example.com",
+		Role:     "admin",
+		Age:      &age,
+		Password: "secret",
+		Again:    "secret",
+		Start:    now,
+		End:      now.Add(time.Hour),
+		Timeout:  time.Second,
+		Code:     "ab",
+	}
+	valid.Address.City = "Paris"
+	if err := ValidateStruct(&valid); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	young := 12
+	invalid := valid
+	invalid.Name = "g"
+	invalid.Email = "gopher"
+	invalid.Role = "root"
+	invalid.Age = &young
+	invalid.Tags = []string{"a", "b", "c"}
+	invalid.Again = "other"
+	invalid.End = now.Add(-time.Hour)
+	invalid.Timeout = time.Hour
+	invalid.Code = "abc"
+	invalid.Address.City = "P4ris"
+
+	var errs FieldErrors
+	if err := ValidateStruct(&invalid); !errors.As(err, &errs) {
+		t.Fatalf("Expected FieldErrors, got %v", err)
+	}
+	got := make([]string, 0, len(errs))
+	for _, e := range errs {
+		got = append(got, e.Field+":"+e.Rule)
+	}
+	want := "Name:min Email:email Role:oneof Age:gte Tags:max Again:eqfield End:gtfield Timeout:lte Code:even Address.City:alpha"
+	if strings.Join(got, " ") != want {
+		t.Errorf("Expected %s, got %s", want, strings.Join(got, " "))
+	}
+
+	var empty signupRequest
+	errs = nil
+	if err := ValidateStruct(empty); !errors.As(err, &errs) || errs[0].Field != "Name" || errs[0].Msg != "is required" {
+		t.Errorf("Expected Name to be required, got %v", err)
+	}
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Errorf("Expected replacing required to panic")
+			}
+		}()
+		RegisterRule("required", func(reflect.Value, string, reflect.Value) bool { return true })
+	}()
+
+	var unknown struct {
+		Name string `validate:"nosuchrule"`
+	}
+	if err := ValidateStruct(&unknown); err == nil || errors.As(err, &errs) {
+		t.Errorf("Expected an unknown rule error, got %v", err)
+	}
+}
+
+type paging struct {
+	Page int `query:"page" validate:"min=1"`
+}
+
+type createUserController struct {
+	RestController
+}
+
+func (c *createUserController) Serve(ctx context.Context) error {
+	var req struct {
+		paging
+		Name  string `json:"name" validate:"required"`
+		Email string `json:"email" validate:"email"`
+	}
+	if err := c.Bind(&req); err != nil {
+		return err
+	}
+	c.ServeData(req.Name)
+	return nil
+}
+
+func TestBindValidation(t *testing.T) {
+	s := newTestServer()
+	s.OnPost("/users", &createUserController{})
+
+	req := httptest.NewRequest(http.MethodPost, "/users?page=0", strings.NewReader(`{"email":"nope"}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("Expected status 400, got %d", w.Code)
+	}
+
+	var res struct {
+		Status int           `json:"status"`
+		Data   []*FieldError `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil {
+		t.Fatalf("Unexpected body %s", w.Body.String())
+	}
+	if res.Status != http.StatusBadRequest || len(res.Data) != 3 {
+		t.Errorf("Expected 3 field errors, got %s", w.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/users?page=x", strings.NewReader(`{`))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for a malformed body, got %d", w.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/users?page=1", strings.NewReader(`{"name":"gopher","email":"gopher@example.com"}`))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, req)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "gopher") {
+		t.Errorf("Expected status 200, got %d %s", w.Code, w.Body.String())
+	}
+}